@@ -1,10 +1,14 @@
 package cloud
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -113,14 +117,25 @@ func (pc *ProviderConfig) Update(updateFunc func(*CustomPricing) error) (*Custom
 	// We're about to write the updated values, so we don't want to double write.
 	c, _ := pc.loadConfig(false)
 
-	// Execute Update - On error, return the in-memory config but don't update cache
-	// explicitly
-	err := updateFunc(c)
+	// Execute Update on a copy, so that c (which may be the cached config) is left
+	// untouched if the update fails or produces invalid prices
+	updated := *c
+	err := updateFunc(&updated)
 	if err != nil {
 		return c, err
 	}
 
+	// Reject prices which can't be used at all; merely suspicious prices are
+	// logged, so that a suspicious stored config doesn't block unrelated updates
+	if err := updated.validatePrices(); err != nil {
+		return c, err
+	}
+	for _, warning := range updated.suspiciousPrices() {
+		klog.Infof("[Warning] custom pricing %s", warning)
+	}
+
 	// Cache Update (possible the ptr already references the cached value)
+	*c = updated
 	pc.customPricing = c
 
 	cj, err := json.Marshal(c)
@@ -203,6 +218,195 @@ func SetCustomPricingField(obj *CustomPricing, name string, value string) error
 	return nil
 }
 
+// customPricingPriceFields are the CustomPricing fields holding prices, which
+// are stored as strings but must parse as non-negative floats.
+var customPricingPriceFields = []string{
+	"CPU",
+	"SpotCPU",
+	"RAM",
+	"SpotRAM",
+	"GPU",
+	"SpotGPU",
+	"Storage",
+	"ZoneNetworkEgress",
+	"RegionNetworkEgress",
+	"InternetNetworkEgress",
+	"FirstFiveForwardingRulesCost",
+	"AdditionalForwardingRuleCost",
+	"LBIngressDataCost",
+}
+
+// maxCustomCPUHourlyPrice is the hourly per-core price above which a custom
+// CPU price is assumed to be a typo (e.g. a monthly price entered as hourly).
+const maxCustomCPUHourlyPrice = 100.0
+
+// Validate checks that every price field of the CustomPricing parses as a
+// non-negative float, naming the offending field in the returned error. Empty
+// fields are treated as unset. When custom prices are enabled, suspicious
+// values (a CPU price above $100/hour or a RAM price of zero) are also
+// reported, since they otherwise produce silently wrong costs.
+func (cp *CustomPricing) Validate() error {
+	if cp == nil {
+		return fmt.Errorf("custom pricing is nil")
+	}
+
+	problems := append(cp.invalidPrices(), cp.suspiciousPrices()...)
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid custom pricing: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// validatePrices returns an error if any price field does not parse as a
+// finite, non-negative float. Unlike Validate, suspicious prices are not
+// reported.
+func (cp *CustomPricing) validatePrices() error {
+	if problems := cp.invalidPrices(); len(problems) > 0 {
+		return fmt.Errorf("invalid custom pricing: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// invalidPrices describes each price field which does not parse as a finite,
+// non-negative float.
+func (cp *CustomPricing) invalidPrices() []string {
+	structValue := reflect.ValueOf(cp).Elem()
+
+	var problems []string
+	for _, name := range customPricingPriceFields {
+		value := structValue.FieldByName(name).String()
+		if value == "" {
+			continue
+		}
+
+		price, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: '%s' is not a valid number", name, value))
+			continue
+		}
+		if price < 0 || math.IsNaN(price) || math.IsInf(price, 0) {
+			problems = append(problems, fmt.Sprintf("%s: '%s' must be a finite, non-negative number", name, value))
+		}
+	}
+
+	return problems
+}
+
+// suspiciousPrices describes prices which parse but are likely mistakes, when
+// custom prices are enabled.
+func (cp *CustomPricing) suspiciousPrices() []string {
+	if cp.CustomPricesEnabled != "true" {
+		return nil
+	}
+
+	var problems []string
+	if cpu, err := strconv.ParseFloat(cp.CPU, 64); err == nil && cpu > maxCustomCPUHourlyPrice {
+		problems = append(problems, fmt.Sprintf("CPU: '%s' exceeds $%.0f per core-hour; is it a monthly price?", cp.CPU, maxCustomCPUHourlyPrice))
+	}
+	if ram, err := strconv.ParseFloat(cp.RAM, 64); cp.RAM == "" || (err == nil && ram == 0) {
+		problems = append(problems, "RAM: price is zero while custom prices are enabled")
+	}
+
+	return problems
+}
+
+// Hash returns a digest of the CustomPricing fields which affect computed
+// costs, suitable for keying caches by pricing configuration. Provider,
+// Description and ReadOnly are left out, as are credentials such as
+// ServiceKeySecret and AzureClientSecret, so that rotating a key does not
+// invalidate cached results and no secret is derivable from the digest. The
+// SharedCosts map is hashed in key order, and nil and empty maps hash
+// identically, so the digest does not depend on how the configuration was
+// constructed.
+func (cp *CustomPricing) Hash() string {
+	h := sha256.New()
+	if cp == nil {
+		return hex.EncodeToString(h.Sum(nil))
+	}
+
+	// each string is length-prefixed so that adjacent values cannot run
+	// together and collide, e.g. ("ab", "c") and ("a", "bc")
+	write := func(s string) {
+		fmt.Fprintf(h, "%d:%s", len(s), s)
+	}
+
+	fields := []struct {
+		name  string
+		value string
+	}{
+		{"CPU", cp.CPU},
+		{"SpotCPU", cp.SpotCPU},
+		{"RAM", cp.RAM},
+		{"SpotRAM", cp.SpotRAM},
+		{"GPU", cp.GPU},
+		{"SpotGPU", cp.SpotGPU},
+		{"Storage", cp.Storage},
+		{"ZoneNetworkEgress", cp.ZoneNetworkEgress},
+		{"RegionNetworkEgress", cp.RegionNetworkEgress},
+		{"InternetNetworkEgress", cp.InternetNetworkEgress},
+		{"FirstFiveForwardingRulesCost", cp.FirstFiveForwardingRulesCost},
+		{"AdditionalForwardingRuleCost", cp.AdditionalForwardingRuleCost},
+		{"LBIngressDataCost", cp.LBIngressDataCost},
+		{"SpotLabel", cp.SpotLabel},
+		{"SpotLabelValue", cp.SpotLabelValue},
+		{"GpuLabel", cp.GpuLabel},
+		{"GpuLabelValue", cp.GpuLabelValue},
+		{"SpotDataRegion", cp.SpotDataRegion},
+		{"SpotDataBucket", cp.SpotDataBucket},
+		{"SpotDataPrefix", cp.SpotDataPrefix},
+		{"ProjectID", cp.ProjectID},
+		{"AthenaProjectID", cp.AthenaProjectID},
+		{"AthenaBucketName", cp.AthenaBucketName},
+		{"AthenaRegion", cp.AthenaRegion},
+		{"AthenaDatabase", cp.AthenaDatabase},
+		{"AthenaTable", cp.AthenaTable},
+		{"MasterPayerARN", cp.MasterPayerARN},
+		{"BillingDataDataset", cp.BillingDataDataset},
+		{"CustomPricesEnabled", cp.CustomPricesEnabled},
+		{"DefaultIdle", cp.DefaultIdle},
+		{"AzureSubscriptionID", cp.AzureSubscriptionID},
+		{"AzureBillingRegion", cp.AzureBillingRegion},
+		{"CurrencyCode", cp.CurrencyCode},
+		{"Discount", cp.Discount},
+		{"NegotiatedDiscount", cp.NegotiatedDiscount},
+		{"ClusterName", cp.ClusterName},
+		{"SharedNamespaces", cp.SharedNamespaces},
+		{"SharedLabelNames", cp.SharedLabelNames},
+		{"SharedLabelValues", cp.SharedLabelValues},
+	}
+	for _, field := range fields {
+		write(field.name)
+		write(field.value)
+	}
+
+	keys := make([]string, 0, len(cp.SharedCosts))
+	for k := range cp.SharedCosts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	write("SharedCosts")
+	write(strconv.Itoa(len(keys)))
+	for _, k := range keys {
+		write(k)
+		write(cp.SharedCosts[k])
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Equals returns true if both CustomPricing hold identical values in every
+// field covered by Hash, treating nil and empty maps as equal.
+func (cp *CustomPricing) Equals(other *CustomPricing) bool {
+	if cp == nil || other == nil {
+		return cp == other
+	}
+
+	return cp.Hash() == other.Hash()
+}
+
 // File exists has three different return cases that should be handled:
 //   1. File exists and is not a directory (true, nil)
 //   2. File does not exist (false, nil)
@@ -1308,7 +1308,7 @@ func ClusterCostsOverTime(cli prometheus.Client, provider cloud.Provider, startS
 		klog.V(1).Infof("Error parsing time " + endString + ". Error: " + err.Error())
 		return nil, err
 	}
-	window, err := time.ParseDuration(windowString)
+	window, err := util.ParseWindowDuration(windowString)
 	if err != nil {
 		klog.V(1).Infof("Error parsing time " + windowString + ". Error: " + err.Error())
 		return nil, err
@@ -0,0 +1,84 @@
+package costmodel
+
+import (
+	"github.com/kubecost/cost-model/pkg/clustercache"
+)
+
+// ControllerLabels maps a Deployment, StatefulSet or DaemonSet to its labels,
+// with label names sanitized the same way as pod labels. Keys have the form
+// "namespace,kind,name,clusterID", as built by controllerLabelsKey.
+type ControllerLabels map[string]map[string]string
+
+// controllerLabelsKey builds the ControllerLabels key for a controller. kind is
+// one of the lowercase kinds returned by CostData.GetController.
+func controllerLabelsKey(namespace, kind, name, clusterID string) string {
+	return namespace + "," + kind + "," + name + "," + clusterID
+}
+
+// GetControllerLabels builds the ControllerLabels for all Deployments,
+// StatefulSets and DaemonSets in the given cluster cache.
+func GetControllerLabels(cache clustercache.ClusterCache, clusterID string) ControllerLabels {
+	controllerLabels := make(ControllerLabels)
+
+	add := func(namespace, kind, name string, ls map[string]string) {
+		if len(ls) == 0 {
+			return
+		}
+
+		sanitized := make(map[string]string, len(ls))
+		for k, v := range ls {
+			sanitized[SanitizeLabelName(k)] = v
+		}
+		controllerLabels[controllerLabelsKey(namespace, kind, name, clusterID)] = sanitized
+	}
+
+	for _, d := range cache.GetAllDeployments() {
+		add(d.Namespace, "deployment", d.Name, d.Labels)
+	}
+	for _, ss := range cache.GetAllStatefulSets() {
+		add(ss.Namespace, "statefulset", ss.Name, ss.Labels)
+	}
+	for _, ds := range cache.GetAllDaemonSets() {
+		add(ds.Namespace, "daemonset", ds.Name, ds.Labels)
+	}
+
+	return controllerLabels
+}
+
+// InheritControllerLabels merges the labels of each CostData's owning
+// Deployment, StatefulSet or DaemonSet into its Labels, so that label
+// aggregation also picks up labels which were only set on the controller. Pod
+// labels win when both define the same label, where names are compared after
+// sanitizing, since pod labels may carry either raw or sanitized names. Labels
+// is replaced with a new map rather than modified, as it may be shared with
+// the cluster cache.
+func InheritControllerLabels(costData map[string]*CostData, controllerLabels ControllerLabels) {
+	for _, cd := range costData {
+		if cd == nil {
+			continue
+		}
+
+		name, kind, hasController := cd.GetController()
+		if !hasController {
+			continue
+		}
+
+		ls, ok := controllerLabels[controllerLabelsKey(cd.Namespace, kind, name, cd.ClusterID)]
+		if !ok {
+			continue
+		}
+
+		merged := make(map[string]string, len(cd.Labels)+len(ls))
+		podLabelNames := make(map[string]bool, len(cd.Labels))
+		for k, v := range cd.Labels {
+			merged[k] = v
+			podLabelNames[SanitizeLabelName(k)] = true
+		}
+		for k, v := range ls {
+			if !podLabelNames[k] {
+				merged[k] = v
+			}
+		}
+		cd.Labels = merged
+	}
+}
@@ -763,31 +763,40 @@ func findDeletedNodeInfo(cli prometheusClient.Client, missingNodes map[string]*c
 }
 
 func getContainerAllocation(req []*util.Vector, used []*util.Vector, allocationType string) []*util.Vector {
-	// The result of the normalize operation will be a new []*util.Vector to replace the requests
-	allocationOp := func(r *util.Vector, x *float64, y *float64) bool {
-		if x != nil && y != nil {
-			x1 := *x
-			if math.IsNaN(x1) {
-				klog.V(1).Infof("[Warning] NaN value found during %s allocation calculation for requests.", allocationType)
-				x1 = 0.0
-			}
-			y1 := *y
-			if math.IsNaN(y1) {
-				klog.V(1).Infof("[Warning] NaN value found during %s allocation calculation for used.", allocationType)
-				y1 = 0.0
-			}
-
-			r.Value = math.Max(x1, y1)
-		} else if x != nil {
-			r.Value = *x
-		} else if y != nil {
-			r.Value = *y
+	req = replaceNaNWithZero(req, fmt.Sprintf("%s allocation calculation for requests", allocationType))
+	used = replaceNaNWithZero(used, fmt.Sprintf("%s allocation calculation for used", allocationType))
+
+	// allocation is the greater of requests and usage; duplicate samples (e.g.
+	// from an HA pair of Prometheus servers) are averaged rather than summed
+	return util.MaxVectors(req, used)
+}
+
+// replaceNaNWithZero returns vs with any NaN values replaced by zero, copying
+// the slice only if it contains NaNs.
+func replaceNaNWithZero(vs []*util.Vector, context string) []*util.Vector {
+	for i, v := range vs {
+		if !math.IsNaN(v.Value) {
+			continue
 		}
 
-		return true
+		klog.V(1).Infof("[Warning] NaN value found during %s.", context)
+
+		result := make([]*util.Vector, len(vs))
+		copy(result, vs[:i])
+		for j := i; j < len(vs); j++ {
+			value := vs[j].Value
+			if math.IsNaN(value) {
+				value = 0.0
+			}
+			result[j] = &util.Vector{
+				Timestamp: vs[j].Timestamp,
+				Value:     value,
+			}
+		}
+		return result
 	}
 
-	return util.ApplyVectorOp(req, used, allocationOp)
+	return vs
 }
 
 func addPVData(cache clustercache.ClusterCache, pvClaimMapping map[string]*PersistentVolumeClaimData, cloud costAnalyzerCloud.Provider) error {
@@ -1489,7 +1498,7 @@ func (cm *CostModel) costDataRange(cli prometheusClient.Client, clientset kubern
 		return nil, err
 	}
 
-	window, err := time.ParseDuration(windowString)
+	window, err := util.ParseWindowDuration(windowString)
 	if err != nil {
 		klog.V(1).Infof("Error parsing time " + windowString + ". Error: " + err.Error())
 		return nil, err
@@ -2028,7 +2037,7 @@ func applyAllocationToRequests(allocationMap map[string][]*util.Vector, requestM
 		}
 
 		// Replace request map with normalized
-		requestMap[k] = util.ApplyVectorOp(allocations, requests, normalizeOp)
+		requestMap[k] = util.ApplyVectorOpWithPolicy(allocations, requests, normalizeOp, util.DuplicateAverage)
 	}
 }
 
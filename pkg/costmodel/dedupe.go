@@ -0,0 +1,142 @@
+package costmodel
+
+import (
+	"sort"
+
+	"github.com/kubecost/cost-model/pkg/util"
+)
+
+// DeduplicationReport summarizes the changes made by DeduplicateCostData
+type DeduplicationReport struct {
+	// Merged maps the key of each retained CostData to the keys of the
+	// duplicate entries which were merged into it and removed
+	Merged map[string][]string `json:"merged"`
+	// OverlappingPoints counts the vector points which were dropped because
+	// the retained entry already had a value at the same timestamp
+	OverlappingPoints int `json:"overlappingPoints"`
+}
+
+// DeduplicateCostData finds CostData entries describing the same container,
+// i.e. having the same cluster, namespace, pod and container name, which
+// happens when cost data is collected over overlapping ranges and would
+// otherwise be double counted. Each set of duplicates is merged into the entry
+// whose key sorts first, keeping a single value per timestamp, and the other
+// entries are removed from costData.
+func DeduplicateCostData(costData map[string]*CostData) *DeduplicationReport {
+	report := &DeduplicationReport{
+		Merged: make(map[string][]string),
+	}
+
+	keys := make([]string, 0, len(costData))
+	for key := range costData {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	retained := make(map[string]string, len(costData))
+	for _, key := range keys {
+		cd := costData[key]
+		if cd == nil {
+			continue
+		}
+
+		containerKey := cd.ClusterID + "," + cd.Namespace + "," + cd.PodName + "," + cd.Name
+		retainedKey, ok := retained[containerKey]
+		if !ok {
+			retained[containerKey] = key
+			continue
+		}
+
+		report.OverlappingPoints += mergeCostData(costData[retainedKey], cd)
+		report.Merged[retainedKey] = append(report.Merged[retainedKey], key)
+		delete(costData, key)
+	}
+
+	return report
+}
+
+// mergeCostData merges the vectors of other into cd, keeping the values of cd
+// where both have a value at the same timestamp, and returns the number of
+// such overlapping points.
+func mergeCostData(cd *CostData, other *CostData) int {
+	overlap := 0
+
+	cd.RAMReq = mergeVectorsKeepFirst(cd.RAMReq, other.RAMReq, &overlap)
+	cd.RAMUsed = mergeVectorsKeepFirst(cd.RAMUsed, other.RAMUsed, &overlap)
+	cd.RAMAllocation = mergeVectorsKeepFirst(cd.RAMAllocation, other.RAMAllocation, &overlap)
+	cd.CPUReq = mergeVectorsKeepFirst(cd.CPUReq, other.CPUReq, &overlap)
+	cd.CPUUsed = mergeVectorsKeepFirst(cd.CPUUsed, other.CPUUsed, &overlap)
+	cd.CPUAllocation = mergeVectorsKeepFirst(cd.CPUAllocation, other.CPUAllocation, &overlap)
+	cd.GPUReq = mergeVectorsKeepFirst(cd.GPUReq, other.GPUReq, &overlap)
+	cd.NetworkData = mergeVectorsKeepFirst(cd.NetworkData, other.NetworkData, &overlap)
+
+	for _, otherPVC := range other.PVCData {
+		if otherPVC == nil {
+			continue
+		}
+
+		merged := false
+		for _, pvc := range cd.PVCData {
+			if pvc != nil && pvc.Claim == otherPVC.Claim && pvc.Namespace == otherPVC.Namespace {
+				pvc.Values = mergeVectorsKeepFirst(pvc.Values, otherPVC.Values, &overlap)
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			cd.PVCData = append(cd.PVCData, otherPVC)
+		}
+	}
+
+	if cd.NodeData == nil {
+		cd.NodeData = other.NodeData
+	}
+
+	return overlap
+}
+
+// mergeVectorsKeepFirst joins two vector slices, taking the value from xvs
+// where both have a value at the same timestamp and counting those
+// timestamps in overlap. Values sharing a timestamp within the same slice are
+// averaged first, as they are repeated samples of the same gauge. Vectors with
+// a zero timestamp, which mark missing data, are kept at the front of the
+// result, taken from xvs if it has any and from yvs otherwise.
+func mergeVectorsKeepFirst(xvs []*util.Vector, yvs []*util.Vector, overlap *int) []*util.Vector {
+	keepFirstOp := func(result *util.Vector, x *float64, y *float64) bool {
+		if x != nil && y != nil {
+			*overlap++
+			result.Value = *x
+		} else if x != nil {
+			result.Value = *x
+		} else if y != nil {
+			result.Value = *y
+		}
+
+		return true
+	}
+
+	// an empty input is returned as is by the join, placeholders included
+	if len(xvs) == 0 || len(yvs) == 0 {
+		return util.ApplyVectorOpWithPolicy(xvs, yvs, keepFirstOp, util.DuplicateAverage)
+	}
+
+	placeholders := zeroTimestampVectors(xvs)
+	if len(placeholders) == 0 {
+		placeholders = zeroTimestampVectors(yvs)
+	}
+
+	return append(placeholders, util.ApplyVectorOpWithPolicy(xvs, yvs, keepFirstOp, util.DuplicateAverage)...)
+}
+
+// zeroTimestampVectors returns copies of the vectors with a zero timestamp
+func zeroTimestampVectors(vs []*util.Vector) []*util.Vector {
+	var result []*util.Vector
+	for _, v := range vs {
+		if v != nil && v.Timestamp == 0 {
+			result = append(result, &util.Vector{
+				Value: v.Value,
+			})
+		}
+	}
+	return result
+}
@@ -0,0 +1,246 @@
+// Package generator produces reproducible, synthetic CostData for use in
+// tests and benchmarks.
+package generator
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/kubecost/cost-model/pkg/cloud"
+	"github.com/kubecost/cost-model/pkg/costmodel"
+	"github.com/kubecost/cost-model/pkg/util"
+)
+
+const gib = 1024.0 * 1024.0 * 1024.0
+
+// Spec describes the cost data to generate. Zero values are replaced with the
+// defaults noted on each field.
+type Spec struct {
+	// Seed makes generation reproducible; equal specs generate equal data
+	Seed int64
+	// ClusterID of the generated data (default "cluster-one")
+	ClusterID string
+	// Namespaces is the number of namespaces (default 1)
+	Namespaces int
+	// PodsPerNamespace is the number of pods in each namespace (default 1)
+	PodsPerNamespace int
+	// ContainersPerPod is the number of containers in each pod (default 1)
+	ContainersPerPod int
+	// DeploymentsPerNamespace is the number of deployments the pods of each
+	// namespace are spread across (default 1)
+	DeploymentsPerNamespace int
+	// Nodes is the number of nodes pods are scheduled onto (default 1)
+	Nodes int
+	// Start of the window (default 2020-01-01T00:00:00Z)
+	Start time.Time
+	// Window is the duration covered by each vector (default 24h)
+	Window time.Duration
+	// Resolution is the step between vector points (default 1h)
+	Resolution time.Duration
+	// Labels maps each label name to the values it is drawn from, uniformly,
+	// for every pod
+	Labels map[string][]string
+	// SpotFraction is the fraction of nodes which are spot nodes
+	SpotFraction float64
+	// PVCFraction is the probability that a pod has a persistent volume claim
+	PVCFraction float64
+	// MissingDataProbability is the probability that any given vector point
+	// is missing, e.g. due to a failed scrape
+	MissingDataProbability float64
+}
+
+func (s Spec) withDefaults() Spec {
+	if s.ClusterID == "" {
+		s.ClusterID = "cluster-one"
+	}
+	if s.Namespaces <= 0 {
+		s.Namespaces = 1
+	}
+	if s.PodsPerNamespace <= 0 {
+		s.PodsPerNamespace = 1
+	}
+	if s.ContainersPerPod <= 0 {
+		s.ContainersPerPod = 1
+	}
+	if s.DeploymentsPerNamespace <= 0 {
+		s.DeploymentsPerNamespace = 1
+	}
+	if s.Nodes <= 0 {
+		s.Nodes = 1
+	}
+	if s.Start.IsZero() {
+		s.Start = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+	if s.Window <= 0 {
+		s.Window = 24 * time.Hour
+	}
+	if s.Resolution <= 0 {
+		s.Resolution = time.Hour
+	}
+	return s
+}
+
+// GenerateCostData generates Namespaces x PodsPerNamespace x ContainersPerPod
+// CostData, keyed the same way as the cost model keys container data.
+func GenerateCostData(spec Spec) map[string]*costmodel.CostData {
+	spec = spec.withDefaults()
+	rng := rand.New(rand.NewSource(spec.Seed))
+
+	nodes := generateNodes(rng, spec)
+
+	// iterate label names in order so that generation is reproducible
+	labelNames := make([]string, 0, len(spec.Labels))
+	for name := range spec.Labels {
+		labelNames = append(labelNames, name)
+	}
+	sort.Strings(labelNames)
+
+	var timestamps []float64
+	end := spec.Start.Add(spec.Window)
+	for t := spec.Start; t.Before(end); t = t.Add(spec.Resolution) {
+		timestamps = append(timestamps, float64(t.Unix()))
+	}
+
+	costData := make(map[string]*costmodel.CostData)
+
+	for n := 0; n < spec.Namespaces; n++ {
+		namespace := fmt.Sprintf("namespace-%d", n)
+
+		for p := 0; p < spec.PodsPerNamespace; p++ {
+			podName := fmt.Sprintf("%s-pod-%d", namespace, p)
+			deployment := fmt.Sprintf("%s-deployment-%d", namespace, p%spec.DeploymentsPerNamespace)
+			nodeName := fmt.Sprintf("node-%d", rng.Intn(spec.Nodes))
+
+			labels := make(map[string]string, len(labelNames))
+			for _, name := range labelNames {
+				values := spec.Labels[name]
+				if len(values) > 0 {
+					labels[name] = values[rng.Intn(len(values))]
+				}
+			}
+
+			// missing points are shared by all containers of a pod, as a
+			// failed scrape would be
+			present := make([]bool, len(timestamps))
+			for i := range present {
+				present[i] = rng.Float64() >= spec.MissingDataProbability
+			}
+
+			var pvcs []*costmodel.PersistentVolumeClaimData
+			if rng.Float64() < spec.PVCFraction {
+				pvcs = append(pvcs, generatePVC(rng, spec, namespace, podName, timestamps, present))
+			}
+
+			for c := 0; c < spec.ContainersPerPod; c++ {
+				containerName := fmt.Sprintf("container-%d", c)
+
+				cpuReq := 0.1 + rng.Float64()*3.9
+				ramReq := (0.125 + rng.Float64()*7.875) * gib
+
+				cd := &costmodel.CostData{
+					Name:        containerName,
+					PodName:     podName,
+					NodeName:    nodeName,
+					NodeData:    nodes[nodeName],
+					Namespace:   namespace,
+					Deployments: []string{deployment},
+					Labels:      labels,
+					ClusterID:   spec.ClusterID,
+					ClusterName: spec.ClusterID,
+				}
+
+				// only the first container of a pod carries its volumes, so
+				// that they are not counted once per container
+				if c == 0 {
+					cd.PVCData = pvcs
+				}
+
+				for i, ts := range timestamps {
+					if !present[i] {
+						continue
+					}
+
+					cpuUsed := cpuReq * (0.1 + rng.Float64()*1.1)
+					ramUsed := ramReq * (0.1 + rng.Float64()*1.1)
+
+					cd.CPUReq = append(cd.CPUReq, &util.Vector{Timestamp: ts, Value: cpuReq})
+					cd.CPUUsed = append(cd.CPUUsed, &util.Vector{Timestamp: ts, Value: cpuUsed})
+					cd.CPUAllocation = append(cd.CPUAllocation, &util.Vector{Timestamp: ts, Value: maxFloat(cpuReq, cpuUsed)})
+					cd.RAMReq = append(cd.RAMReq, &util.Vector{Timestamp: ts, Value: ramReq})
+					cd.RAMUsed = append(cd.RAMUsed, &util.Vector{Timestamp: ts, Value: ramUsed})
+					cd.RAMAllocation = append(cd.RAMAllocation, &util.Vector{Timestamp: ts, Value: maxFloat(ramReq, ramUsed)})
+				}
+
+				key := costmodel.NewContainerMetricFromValues(namespace, podName, containerName, nodeName, spec.ClusterID).Key()
+				costData[key] = cd
+			}
+		}
+	}
+
+	return costData
+}
+
+// generateNodes creates the nodes of the spec, SpotFraction of which are spot
+// nodes priced at a discount.
+func generateNodes(rng *rand.Rand, spec Spec) map[string]*cloud.Node {
+	defaults := cloud.DefaultPricing()
+
+	nodes := make(map[string]*cloud.Node, spec.Nodes)
+	for i := 0; i < spec.Nodes; i++ {
+		node := &cloud.Node{
+			VCPU:         "8",
+			VCPUCost:     defaults.CPU,
+			RAM:          "32Gi",
+			RAMBytes:     fmt.Sprintf("%d", int64(32*gib)),
+			RAMCost:      defaults.RAM,
+			InstanceType: "synthetic-8x32",
+			Region:       "synthetic-region",
+			UsageType:    "ondemand",
+		}
+		if rng.Float64() < spec.SpotFraction {
+			node.VCPUCost = defaults.SpotCPU
+			node.RAMCost = defaults.SpotRAM
+			node.UsageType = "spot"
+		}
+		nodes[fmt.Sprintf("node-%d", i)] = node
+	}
+
+	return nodes
+}
+
+// generatePVC creates a persistent volume claim of 1-100GiB for the given pod
+func generatePVC(rng *rand.Rand, spec Spec, namespace, podName string, timestamps []float64, present []bool) *costmodel.PersistentVolumeClaimData {
+	size := float64(1+rng.Intn(100)) * gib
+	volumeName := fmt.Sprintf("pvc-%s", podName)
+
+	pvc := &costmodel.PersistentVolumeClaimData{
+		Class:        "standard",
+		Claim:        fmt.Sprintf("%s-data", podName),
+		Namespace:    namespace,
+		ClusterID:    spec.ClusterID,
+		TimesClaimed: 1,
+		VolumeName:   volumeName,
+		Volume: &cloud.PV{
+			Cost:  cloud.DefaultPricing().Storage,
+			Class: "standard",
+			Size:  fmt.Sprintf("%d", int64(size)),
+		},
+	}
+
+	for i, ts := range timestamps {
+		if present[i] {
+			pvc.Values = append(pvc.Values, &util.Vector{Timestamp: ts, Value: size})
+		}
+	}
+
+	return pvc
+}
+
+func maxFloat(x, y float64) float64 {
+	if x > y {
+		return x
+	}
+	return y
+}
@@ -0,0 +1,228 @@
+package costmodel
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/kubecost/cost-model/pkg/util"
+	"k8s.io/klog"
+)
+
+// CostDataIssueType categorizes a problem found by ValidateCostData
+type CostDataIssueType string
+
+const (
+	IssueMissingNodeData     CostDataIssueType = "missingNodeData"
+	IssueUnparsableCost      CostDataIssueType = "unparsableCost"
+	IssueTimestampOutOfRange CostDataIssueType = "timestampOutOfRange"
+	IssueFutureTimestamp     CostDataIssueType = "futureTimestamp"
+	IssueTimestampBeforeSane CostDataIssueType = "timestampBeforeSane"
+	IssueNegativeValue       CostDataIssueType = "negativeValue"
+	IssueNoClusterNodeData   CostDataIssueType = "noClusterNodeData"
+)
+
+// CostDataIssue describes a single data-quality problem in cost data
+type CostDataIssue struct {
+	Type    CostDataIssueType `json:"type"`
+	Message string            `json:"message"`
+}
+
+// CostDataReport is the result of ValidateCostData. Data holds the issues
+// found on individual CostData, by cost data key, and Clusters holds the
+// dataset-level issues, by cluster ID.
+type CostDataReport struct {
+	Data     map[string][]*CostDataIssue `json:"data"`
+	Clusters map[string][]*CostDataIssue `json:"clusters"`
+}
+
+// HasIssues returns true if the report contains any issues
+func (r *CostDataReport) HasIssues() bool {
+	return r != nil && (len(r.Data) > 0 || len(r.Clusters) > 0)
+}
+
+func (r *CostDataReport) addDataIssue(key string, issueType CostDataIssueType, format string, args ...interface{}) {
+	r.Data[key] = append(r.Data[key], &CostDataIssue{
+		Type:    issueType,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (r *CostDataReport) addClusterIssue(clusterID string, issueType CostDataIssueType, format string, args ...interface{}) {
+	r.Clusters[clusterID] = append(r.Clusters[clusterID], &CostDataIssue{
+		Type:    issueType,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// ValidateCostData checks cost data for problems which would otherwise flow
+// silently into cost figures: allocations without node data, including nodes
+// with neither a CPU nor a RAM cost such as the empty placeholders inserted
+// for nodes missing from the range query, node or volume
+// prices which do not parse, timestamps more than one step outside of the
+// window [start, end), negative values, and clusters for which no cost data
+// carries node data at all. Timestamps more than futureTolerance past end, or
+// before util.MinSaneTimestamp, are reported separately as clock skew. Issues
+// for each CostData are reported in a fixed order.
+func ValidateCostData(costData map[string]*CostData, start, end time.Time, step time.Duration, futureTolerance time.Duration) *CostDataReport {
+	report := &CostDataReport{
+		Data:     make(map[string][]*CostDataIssue),
+		Clusters: make(map[string][]*CostDataIssue),
+	}
+
+	minTimestamp := float64(start.Add(-step).Unix())
+	maxTimestamp := float64(end.Add(step).Unix())
+	maxSkewedTimestamp := float64(end.Add(futureTolerance).Unix())
+
+	clusterHasNodeData := make(map[string]bool)
+
+	for key, cd := range costData {
+		if cd == nil {
+			continue
+		}
+
+		if _, ok := clusterHasNodeData[cd.ClusterID]; !ok {
+			clusterHasNodeData[cd.ClusterID] = false
+		}
+
+		hasNodeData := cd.NodeData != nil && (cd.NodeData.VCPUCost != "" || cd.NodeData.RAMCost != "")
+		if hasNodeData {
+			clusterHasNodeData[cd.ClusterID] = true
+		} else if len(cd.CPUAllocation) > 0 || len(cd.RAMAllocation) > 0 || len(cd.GPUReq) > 0 {
+			report.addDataIssue(key, IssueMissingNodeData, "allocation without node data for node '%s'", cd.NodeName)
+		}
+
+		if cd.NodeData != nil {
+			prices := []struct {
+				name  string
+				value string
+			}{
+				{"CPU", cd.NodeData.VCPUCost},
+				{"RAM", cd.NodeData.RAMCost},
+				{"GPU", cd.NodeData.GPUCost},
+			}
+			for _, price := range prices {
+				if price.value == "" {
+					continue
+				}
+				if _, err := strconv.ParseFloat(price.value, 64); err != nil {
+					report.addDataIssue(key, IssueUnparsableCost, "node '%s' %s cost '%s' is not a number", cd.NodeName, price.name, price.value)
+				}
+			}
+		}
+
+		type namedVectors struct {
+			name string
+			vs   []*util.Vector
+		}
+		vectors := []namedVectors{
+			{"RAMReq", cd.RAMReq},
+			{"RAMUsed", cd.RAMUsed},
+			{"RAMAllocation", cd.RAMAllocation},
+			{"CPUReq", cd.CPUReq},
+			{"CPUUsed", cd.CPUUsed},
+			{"CPUAllocation", cd.CPUAllocation},
+			{"GPUReq", cd.GPUReq},
+			{"NetworkData", cd.NetworkData},
+		}
+		for _, pvc := range cd.PVCData {
+			if pvc == nil {
+				continue
+			}
+			vectors = append(vectors, namedVectors{"PVCData[" + pvc.Claim + "]", pvc.Values})
+
+			if pvc.Volume != nil && pvc.Volume.Cost != "" {
+				if _, err := strconv.ParseFloat(pvc.Volume.Cost, 64); err != nil {
+					report.addDataIssue(key, IssueUnparsableCost, "volume '%s' cost '%s' is not a number", pvc.VolumeName, pvc.Volume.Cost)
+				}
+			}
+		}
+
+		for _, nv := range vectors {
+			name, vs := nv.name, nv.vs
+			outOfRange, future, beforeSane, negative := 0, 0, 0, 0
+			for _, v := range vs {
+				// a zero timestamp marks missing data, e.g. []*util.Vector{{}}
+				if v == nil || v.Timestamp == 0 {
+					continue
+				}
+				if v.Timestamp < util.MinSaneTimestamp {
+					beforeSane++
+				} else if v.Timestamp > maxSkewedTimestamp {
+					future++
+				} else if v.Timestamp < minTimestamp || v.Timestamp >= maxTimestamp {
+					outOfRange++
+				}
+				if v.Value < 0 {
+					negative++
+				}
+			}
+
+			if outOfRange > 0 {
+				report.addDataIssue(key, IssueTimestampOutOfRange, "%s has %d of %d points outside of the window", name, outOfRange, len(vs))
+			}
+			if future > 0 {
+				report.addDataIssue(key, IssueFutureTimestamp, "%s has %d of %d points more than %s after the window", name, future, len(vs), futureTolerance)
+			}
+			if beforeSane > 0 {
+				report.addDataIssue(key, IssueTimestampBeforeSane, "%s has %d of %d points before the year 2000", name, beforeSane, len(vs))
+			}
+			if negative > 0 {
+				report.addDataIssue(key, IssueNegativeValue, "%s has %d negative values", name, negative)
+			}
+		}
+	}
+
+	for clusterID, hasNodeData := range clusterHasNodeData {
+		if !hasNodeData {
+			report.addClusterIssue(clusterID, IssueNoClusterNodeData, "no cost data for cluster '%s' has node data", clusterID)
+		}
+	}
+
+	return report
+}
+
+// DropSkewedCostData removes vector points with timestamps more than tolerance
+// after end, or before util.MinSaneTimestamp, from all cost data. Such points
+// come from skewed clocks and would otherwise be summed with real data
+// arriving later for the same timestamps. Zero-timestamp placeholders for
+// missing data are left in place. It returns the number of points dropped.
+func DropSkewedCostData(costData map[string]*CostData, end time.Time, tolerance time.Duration) int {
+	maxTimestamp := float64(end.Add(tolerance).Unix())
+
+	dropped := 0
+	drop := func(vs []*util.Vector) []*util.Vector {
+		if len(vs) == 0 {
+			return vs
+		}
+		kept, n := util.DropSkewedVectors(vs, maxTimestamp)
+		dropped += n
+		return kept
+	}
+
+	for _, cd := range costData {
+		if cd == nil {
+			continue
+		}
+
+		cd.RAMReq = drop(cd.RAMReq)
+		cd.RAMUsed = drop(cd.RAMUsed)
+		cd.RAMAllocation = drop(cd.RAMAllocation)
+		cd.CPUReq = drop(cd.CPUReq)
+		cd.CPUUsed = drop(cd.CPUUsed)
+		cd.CPUAllocation = drop(cd.CPUAllocation)
+		cd.GPUReq = drop(cd.GPUReq)
+		cd.NetworkData = drop(cd.NetworkData)
+		for _, pvc := range cd.PVCData {
+			if pvc != nil {
+				pvc.Values = drop(pvc.Values)
+			}
+		}
+	}
+
+	if dropped > 0 {
+		klog.V(3).Infof("[Warning] dropped %d cost data points with timestamps more than %s after %s or before the year 2000", dropped, tolerance, end.Format(time.RFC3339))
+	}
+
+	return dropped
+}
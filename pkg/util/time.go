@@ -3,6 +3,7 @@ package util
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -15,6 +16,10 @@ const (
 
 // ParseDuration converts a Prometheus-style duration string into a Duration
 func ParseDuration(duration string) (*time.Duration, error) {
+	if duration == "" {
+		return nil, fmt.Errorf("error parsing duration: empty duration")
+	}
+
 	unitStr := duration[len(duration)-1:]
 	var unit time.Duration
 	switch unitStr {
@@ -54,22 +59,101 @@ func ParseTimeRange(duration, offset string) (*time.Time, *time.Time, error) {
 		endTime = endTime.Add(-1 * *o)
 	}
 
+	dur, err := ParseWindowDuration(duration)
+	if err != nil {
+		return nil, nil, err
+	}
+	startTime := endTime.Add(-1 * dur)
+
+	return &startTime, &endTime, nil
+}
+
+// ParseWindow converts a window string into a start and end time, relative to
+// now. Accepted windows are:
+//   - durations, e.g. "30m", "24h" or "7d", ending at now
+//   - the calendar keywords "today", "yesterday", "week", "lastweek", "month"
+//     and "lastmonth", evaluated in loc (UTC if nil) with weeks beginning on
+//     Sunday; current periods end at now, previous periods end where the
+//     current period begins
+//   - explicit RFC3339 ranges, e.g. "2020-05-01T00:00:00Z,2020-05-08T00:00:00Z"
+func ParseWindow(window string, now time.Time, loc *time.Location) (time.Time, time.Time, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	now = now.In(loc)
+
+	window = strings.TrimSpace(window)
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	weekStart := midnight.AddDate(0, 0, -int(now.Weekday()))
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+
+	switch strings.ToLower(window) {
+	case "today":
+		return midnight, now, nil
+	case "yesterday":
+		return midnight.AddDate(0, 0, -1), midnight, nil
+	case "week":
+		return weekStart, now, nil
+	case "lastweek":
+		return weekStart.AddDate(0, 0, -7), weekStart, nil
+	case "month":
+		return monthStart, now, nil
+	case "lastmonth":
+		return monthStart.AddDate(0, -1, 0), monthStart, nil
+	}
+
+	if strings.Contains(window, ",") {
+		bounds := strings.Split(window, ",")
+		if len(bounds) != 2 {
+			return time.Time{}, time.Time{}, fmt.Errorf("error parsing window (%s): expected two comma-separated RFC3339 times", window)
+		}
+
+		start, err := time.Parse(time.RFC3339, strings.TrimSpace(bounds[0]))
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("error parsing window start (%s): %s", bounds[0], err)
+		}
+		end, err := time.Parse(time.RFC3339, strings.TrimSpace(bounds[1]))
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("error parsing window end (%s): %s", bounds[1], err)
+		}
+		if !end.After(start) {
+			return time.Time{}, time.Time{}, fmt.Errorf("error parsing window (%s): end must be after start", window)
+		}
+
+		return start.In(loc), end.In(loc), nil
+	}
+
+	dur, err := ParseWindowDuration(window)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if dur <= 0 {
+		return time.Time{}, time.Time{}, fmt.Errorf("error parsing window (%s): duration must be positive", window)
+	}
+
+	return now.Add(-1 * dur), now, nil
+}
+
+// ParseWindowDuration parses a window duration, accepting anything understood
+// by time.ParseDuration plus whole numbers of days, e.g. "2d".
+func ParseWindowDuration(duration string) (time.Duration, error) {
+	if duration == "" {
+		return 0, fmt.Errorf("error parsing duration: empty duration")
+	}
+
 	// if duration is defined in terms of days, convert to hours
 	// e.g. convert "2d" to "48h"
 	durationNorm, err := normalizeTimeParam(duration)
 	if err != nil {
-		return nil, nil, fmt.Errorf("error parsing duration (%s): %s", duration, err)
+		return 0, fmt.Errorf("error parsing duration (%s): %s", duration, err)
 	}
 
-	// convert time duration into start and end times, formatted
-	// as ISO datetime strings
 	dur, err := time.ParseDuration(durationNorm)
 	if err != nil {
-		return nil, nil, fmt.Errorf("errorf parsing duration (%s): %s", durationNorm, err)
+		return 0, fmt.Errorf("error parsing duration (%s): %s", durationNorm, err)
 	}
-	startTime := endTime.Add(-1 * dur)
 
-	return &startTime, &endTime, nil
+	return dur, nil
 }
 
 func normalizeTimeParam(param string) (string, error) {
@@ -40,8 +40,22 @@ func capacityFor(xvs []*Vector, yvs []*Vector) int {
 }
 
 // ApplyVectorOp accepts two vectors, synchronizes timestamps, and executes an operation
-// on each vector. See VectorJoinOp for details.
+// on each vector. See VectorJoinOp for details. Timestamps are rounded to the nearest
+// 10 seconds, zero-timestamp vectors (placeholders for missing data) are dropped, and
+// values sharing a timestamp within the same input are summed before the op is
+// applied; use ApplyVectorOpWithPolicy to combine them differently.
+//
+// If either input is empty, the other is returned as is, without rounding, dropping
+// placeholders or combining duplicates, and the op is not applied.
 func ApplyVectorOp(xvs []*Vector, yvs []*Vector, op VectorJoinOp) []*Vector {
+	return ApplyVectorOpWithPolicy(xvs, yvs, op, DuplicateSum)
+}
+
+// ApplyVectorOpWithPolicy is ApplyVectorOp with values sharing a (rounded) timestamp
+// within the same input combined according to the given policy. Gauges, such as
+// requests or usage reported twice by an HA pair of Prometheus servers, should be
+// averaged rather than summed.
+func ApplyVectorOpWithPolicy(xvs []*Vector, yvs []*Vector, op VectorJoinOp, policy DuplicatePolicy) []*Vector {
 	// if xvs is empty, return yvs
 	if xvs == nil || len(xvs) == 0 {
 		return yvs
@@ -55,6 +69,13 @@ func ApplyVectorOp(xvs []*Vector, yvs []*Vector, op VectorJoinOp) []*Vector {
 	// timestamps contains the vector slice after joining xvs and yvs
 	var timestamps []*Vector
 
+	// counts of the values at each timestamp, only needed for averaging
+	var xCounts, yCounts map[uint64]int
+	if policy == DuplicateAverage {
+		xCounts = make(map[uint64]int)
+		yCounts = make(map[uint64]int)
+	}
+
 	// turn each vector slice into a map of timestamp-to-value so that
 	// values at equal timestamps can be lined-up and summed
 	xMap := mapPool.Get()
@@ -68,6 +89,17 @@ func ApplyVectorOp(xvs []*Vector, yvs []*Vector, op VectorJoinOp) []*Vector {
 		// round all non-zero timestamps to the nearest 10 second mark
 		xv.Timestamp = roundTimestamp(xv.Timestamp, 10.0)
 
+		if xCounts != nil {
+			xCounts[uint64(xv.Timestamp)]++
+		}
+
+		// duplicate timestamps within a single input are combined, so the
+		// result does not depend on the order of the input
+		if x, ok := xMap[uint64(xv.Timestamp)]; ok {
+			xMap[uint64(xv.Timestamp)] = x + xv.Value
+			continue
+		}
+
 		xMap[uint64(xv.Timestamp)] = xv.Value
 		timestamps = append(timestamps, &Vector{
 			Timestamp: xv.Timestamp,
@@ -85,6 +117,15 @@ func ApplyVectorOp(xvs []*Vector, yvs []*Vector, op VectorJoinOp) []*Vector {
 		// round all non-zero timestamps to the nearest 10 second mark
 		yv.Timestamp = roundTimestamp(yv.Timestamp, 10.0)
 
+		if yCounts != nil {
+			yCounts[uint64(yv.Timestamp)]++
+		}
+
+		if y, ok := yMap[uint64(yv.Timestamp)]; ok {
+			yMap[uint64(yv.Timestamp)] = y + yv.Value
+			continue
+		}
+
 		yMap[uint64(yv.Timestamp)] = yv.Value
 		if _, ok := xMap[uint64(yv.Timestamp)]; !ok {
 			// no need to double add, since we'll range over sorted timestamps and check.
@@ -94,6 +135,13 @@ func ApplyVectorOp(xvs []*Vector, yvs []*Vector, op VectorJoinOp) []*Vector {
 		}
 	}
 
+	for ts, count := range xCounts {
+		xMap[ts] /= float64(count)
+	}
+	for ts, count := range yCounts {
+		yMap[ts] /= float64(count)
+	}
+
 	// iterate over each timestamp to produce a final op vector slice
 	// reuse the existing slice to reduce allocations
 	result := timestamps[:0]
@@ -136,7 +184,8 @@ func VectorValue(v float64, ok bool) *float64 {
 
 // NormalizeVectorByVector produces a version of xvs (a slice of Vectors)
 // which has had its timestamps rounded and its values divided by the values
-// of the Vectors of yvs, such that yvs is the "unit" Vector slice.
+// of the Vectors of yvs, such that yvs is the "unit" Vector slice. Both are
+// gauges, so duplicate samples within either are averaged.
 func NormalizeVectorByVector(xvs []*Vector, yvs []*Vector) []*Vector {
 	normalizeOp := func(result *Vector, x *float64, y *float64) bool {
 		if x != nil && y != nil && *y != 0 {
@@ -150,5 +199,263 @@ func NormalizeVectorByVector(xvs []*Vector, yvs []*Vector) []*Vector {
 		return true
 	}
 
-	return ApplyVectorOp(xvs, yvs, normalizeOp)
+	return ApplyVectorOpWithPolicy(xvs, yvs, normalizeOp, DuplicateAverage)
+}
+
+// MaxVectors produces the element-wise maximum of xvs and yvs, with
+// timestamps aligned and rounded as by ApplyVectorOp. A timestamp present in
+// only one of the inputs passes through with its value unchanged. As max and
+// min compare levels rather than amounts, duplicate timestamps within an input
+// are averaged, not summed. A NaN on either side produces a NaN result, so
+// callers should replace NaNs beforehand if they need a number.
+func MaxVectors(xvs []*Vector, yvs []*Vector) []*Vector {
+	maxOp := func(result *Vector, x *float64, y *float64) bool {
+		if x != nil && y != nil {
+			result.Value = math.Max(*x, *y)
+		} else if x != nil {
+			result.Value = *x
+		} else if y != nil {
+			result.Value = *y
+		}
+
+		return true
+	}
+
+	return ApplyVectorOpWithPolicy(xvs, yvs, maxOp, DuplicateAverage)
+}
+
+// MinVectors produces the element-wise minimum of xvs and yvs, with
+// timestamps aligned and rounded as by ApplyVectorOp. A timestamp present in
+// only one of the inputs is dropped, as there is nothing to overlap with. As
+// with MaxVectors, duplicate timestamps within an input are averaged and a NaN
+// on either side produces a NaN result.
+func MinVectors(xvs []*Vector, yvs []*Vector) []*Vector {
+	// ApplyVectorOp passes a non-empty input through unchanged when the other
+	// is empty, which would not drop its timestamps
+	if len(xvs) == 0 || len(yvs) == 0 {
+		return []*Vector{}
+	}
+
+	minOp := func(result *Vector, x *float64, y *float64) bool {
+		if x == nil || y == nil {
+			return false
+		}
+
+		result.Value = math.Min(*x, *y)
+		return true
+	}
+
+	return ApplyVectorOpWithPolicy(xvs, yvs, minOp, DuplicateAverage)
+}
+
+// DuplicatePolicy determines how values sharing a (rounded) timestamp within
+// a single vector slice are combined.
+type DuplicatePolicy int
+
+const (
+	// DuplicateSum adds the values together. This matches ApplyVectorOp.
+	DuplicateSum DuplicatePolicy = iota
+	// DuplicateAverage takes the mean of the values, which suits inputs such
+	// as Prometheus HA pairs reporting the same sample twice.
+	DuplicateAverage
+)
+
+// DeduplicateVectors returns a new, sorted vector slice holding one vector per
+// timestamp, rounded to the nearest 10 second mark as in ApplyVectorOp, with
+// duplicate values combined according to the given policy. Unlike in
+// ApplyVectorOp, vectors with a zero timestamp, which mark missing data, are
+// kept as they are rather than dropped. The input is not modified.
+func DeduplicateVectors(vs []*Vector, policy DuplicatePolicy) []*Vector {
+	if len(vs) == 0 {
+		return vs
+	}
+
+	result := make([]*Vector, 0, len(vs))
+	counts := make(map[uint64]int, len(vs))
+	indices := make(map[uint64]int, len(vs))
+
+	for _, v := range vs {
+		if v.Timestamp == 0 {
+			result = append(result, &Vector{
+				Value: v.Value,
+			})
+			continue
+		}
+
+		ts := roundTimestamp(v.Timestamp, 10.0)
+
+		if i, ok := indices[uint64(ts)]; ok {
+			result[i].Value += v.Value
+			counts[uint64(ts)]++
+			continue
+		}
+
+		indices[uint64(ts)] = len(result)
+		counts[uint64(ts)] = 1
+		result = append(result, &Vector{
+			Timestamp: ts,
+			Value:     v.Value,
+		})
+	}
+
+	if policy == DuplicateAverage {
+		for _, v := range result {
+			if v.Timestamp != 0 {
+				v.Value /= float64(counts[uint64(v.Timestamp)])
+			}
+		}
+	}
+
+	sort.Sort(VectorSlice(result))
+
+	return result
+}
+
+// TrimVectors returns copies of the vectors whose timestamps, in unix seconds,
+// fall within the window [start, end). If prorate is true, each vector is
+// instead taken to cover the step seconds beginning at its timestamp, and
+// vectors straddling either window boundary are kept with their values scaled
+// by the fraction of the step lying inside the window (and their timestamps
+// moved up to start, if earlier). Vectors with a zero timestamp mark missing
+// data rather than a time outside the window, and are passed through
+// unchanged. The input is not modified.
+func TrimVectors(vs []*Vector, start, end, step float64, prorate bool) []*Vector {
+	result := make([]*Vector, 0, len(vs))
+
+	for _, v := range vs {
+		if v.Timestamp == 0 {
+			result = append(result, &Vector{
+				Value: v.Value,
+			})
+			continue
+		}
+
+		if !prorate || step <= 0 {
+			if v.Timestamp >= start && v.Timestamp < end {
+				result = append(result, &Vector{
+					Timestamp: v.Timestamp,
+					Value:     v.Value,
+				})
+			}
+			continue
+		}
+
+		overlap := math.Min(v.Timestamp+step, end) - math.Max(v.Timestamp, start)
+		if overlap <= 0 {
+			continue
+		}
+
+		result = append(result, &Vector{
+			Timestamp: math.Max(v.Timestamp, start),
+			Value:     v.Value * math.Min(overlap/step, 1.0),
+		})
+	}
+
+	return result
+}
+
+// MinSaneTimestamp is 2000-01-01T00:00:00Z in unix seconds. Earlier vector
+// timestamps can only come from a broken clock or an unset value.
+const MinSaneTimestamp = 946684800.0
+
+// DropSkewedVectors returns copies of the vectors whose timestamps lie within
+// [MinSaneTimestamp, maxTimestamp], along with the number of vectors dropped.
+// Points beyond maxTimestamp typically come from a node with a clock running
+// ahead and would otherwise be merged with the real data for those times once
+// it arrives. Vectors with a zero timestamp mark missing data rather than a
+// skewed clock, and are kept. The input is not modified.
+func DropSkewedVectors(vs []*Vector, maxTimestamp float64) ([]*Vector, int) {
+	result := make([]*Vector, 0, len(vs))
+
+	for _, v := range vs {
+		if v.Timestamp != 0 && (v.Timestamp < MinSaneTimestamp || v.Timestamp > maxTimestamp) {
+			continue
+		}
+		result = append(result, &Vector{
+			Timestamp: v.Timestamp,
+			Value:     v.Value,
+		})
+	}
+
+	return result, len(vs) - len(result)
+}
+
+// maxResampleRatio bounds from/to in ResampleVectors, and so the number of
+// steps each vector is spread across; it allows e.g. weekly data to be spread
+// across minutes.
+const maxResampleRatio = 10080.0
+
+// ResampleVectors redistributes vectors sampled every from seconds onto a
+// common step of to seconds, with resulting timestamps aligned to multiples of
+// to. Each vector is taken to cover the from seconds beginning at its
+// timestamp, and its value is split across the target steps it overlaps in
+// proportion to the overlap, so finer data is summed into each step and
+// coarser data is spread across them. The sum of values is preserved. Vectors
+// with a zero timestamp mark missing data and are passed through unchanged.
+// If from or to is not positive, from/to exceeds maxResampleRatio, or to is
+// too fine a step to represent at the given timestamps, an unresampled copy of
+// the vectors is returned. The input is not modified.
+func ResampleVectors(vs []*Vector, from, to float64) []*Vector {
+	if len(vs) == 0 || from <= 0 || to <= 0 || from/to > maxResampleRatio || !resampleStepRepresentable(vs, to) {
+		result := make([]*Vector, 0, len(vs))
+		for _, v := range vs {
+			result = append(result, &Vector{
+				Timestamp: v.Timestamp,
+				Value:     v.Value,
+			})
+		}
+		return result
+	}
+
+	var placeholders []*Vector
+
+	// buckets are keyed by their index, i.e. their timestamp divided by to,
+	// so that every vector maps the same step to the same key
+	buckets := make(map[int64]float64, len(vs))
+
+	for _, v := range vs {
+		if v.Timestamp == 0 {
+			placeholders = append(placeholders, &Vector{
+				Value: v.Value,
+			})
+			continue
+		}
+
+		end := v.Timestamp + from
+		first := int64(math.Floor(v.Timestamp / to))
+		for i := first; float64(i)*to < end; i++ {
+			b := float64(i) * to
+			overlap := math.Min(b+to, end) - math.Max(b, v.Timestamp)
+			if overlap <= 0 {
+				continue
+			}
+			buckets[i] += v.Value * overlap / from
+		}
+	}
+
+	result := make([]*Vector, 0, len(placeholders)+len(buckets))
+	result = append(result, placeholders...)
+	for i, value := range buckets {
+		result = append(result, &Vector{
+			Timestamp: float64(i) * to,
+			Value:     value,
+		})
+	}
+
+	sort.Sort(VectorSlice(result))
+
+	return result
+}
+
+// resampleStepRepresentable returns true if every timestamp of vs is within
+// 2^53 steps of to seconds of zero, so that step indices and the timestamps
+// derived from them are exact. Finer steps cannot be told apart from the
+// timestamps themselves.
+func resampleStepRepresentable(vs []*Vector, to float64) bool {
+	for _, v := range vs {
+		if math.Abs(v.Timestamp/to) >= 1<<53 {
+			return false
+		}
+	}
+	return true
 }
@@ -0,0 +1,182 @@
+package util
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// vectorSeriesVersion is written as the first byte of every encoded series so
+// that the format can change without older data being misread.
+const vectorSeriesVersion byte = 1
+
+// maxVectorSeriesPrecision bounds the number of decimal places, beyond which
+// float64 values can no longer be represented exactly anyway.
+const maxVectorSeriesPrecision = 15
+
+// VectorSeriesCodec compresses Vector slices, which are typically dominated
+// by timestamps on a fixed step and values of limited precision.
+//
+// Timestamps are quantized to milliseconds and stored as a first value, a
+// first delta and then delta-of-deltas, so that a fixed step costs a single
+// byte per point. Values are quantized to Precision decimal places and stored
+// as deltas. All integers are zig-zag varints.
+type VectorSeriesCodec struct {
+	// Precision is the number of decimal places to which values are kept
+	Precision int
+}
+
+// Encode compresses the given vectors. Values are rounded to the codec's
+// precision; NaN and Inf values, and values too large to quantize, result in
+// an error.
+func (c VectorSeriesCodec) Encode(vs []*Vector) ([]byte, error) {
+	if c.Precision < 0 || c.Precision > maxVectorSeriesPrecision {
+		return nil, fmt.Errorf("vector series precision %d out of range [0, %d]", c.Precision, maxVectorSeriesPrecision)
+	}
+	scale := math.Pow10(c.Precision)
+
+	buf := make([]byte, 0, 3+len(vs)*3)
+	tmp := make([]byte, binary.MaxVarintLen64)
+
+	putUvarint := func(x uint64) {
+		n := binary.PutUvarint(tmp, x)
+		buf = append(buf, tmp[:n]...)
+	}
+	putVarint := func(x int64) {
+		n := binary.PutVarint(tmp, x)
+		buf = append(buf, tmp[:n]...)
+	}
+
+	buf = append(buf, vectorSeriesVersion)
+	putUvarint(uint64(c.Precision))
+	putUvarint(uint64(len(vs)))
+
+	var prevTs, prevDelta, prevValue int64
+	for i, v := range vs {
+		if v == nil {
+			return nil, fmt.Errorf("nil vector at index %d", i)
+		}
+
+		ts, err := quantize(v.Timestamp, 1000)
+		if err != nil {
+			return nil, fmt.Errorf("timestamp at index %d: %s", i, err)
+		}
+		value, err := quantize(v.Value, scale)
+		if err != nil {
+			return nil, fmt.Errorf("value at timestamp %f: %s", v.Timestamp, err)
+		}
+
+		switch i {
+		case 0:
+			putVarint(ts)
+		case 1:
+			prevDelta = ts - prevTs
+			putVarint(prevDelta)
+		default:
+			delta := ts - prevTs
+			putVarint(delta - prevDelta)
+			prevDelta = delta
+		}
+		prevTs = ts
+
+		putVarint(value - prevValue)
+		prevValue = value
+	}
+
+	return buf, nil
+}
+
+// Decode decompresses vectors produced by Encode. The precision is read from
+// the encoded data, so the codec's own Precision is not used.
+func (c VectorSeriesCodec) Decode(b []byte) ([]*Vector, error) {
+	if len(b) == 0 {
+		return nil, fmt.Errorf("empty vector series")
+	}
+	if b[0] != vectorSeriesVersion {
+		return nil, fmt.Errorf("unsupported vector series version %d", b[0])
+	}
+	b = b[1:]
+
+	var err error
+	readUvarint := func() uint64 {
+		if err != nil {
+			return 0
+		}
+		x, n := binary.Uvarint(b)
+		if n <= 0 {
+			err = fmt.Errorf("truncated vector series")
+			return 0
+		}
+		b = b[n:]
+		return x
+	}
+	readVarint := func() int64 {
+		if err != nil {
+			return 0
+		}
+		x, n := binary.Varint(b)
+		if n <= 0 {
+			err = fmt.Errorf("truncated vector series")
+			return 0
+		}
+		b = b[n:]
+		return x
+	}
+
+	precision := readUvarint()
+	count := readUvarint()
+	if err != nil {
+		return nil, err
+	}
+	if precision > maxVectorSeriesPrecision {
+		return nil, fmt.Errorf("vector series precision %d out of range [0, %d]", precision, maxVectorSeriesPrecision)
+	}
+	// every point takes at least two bytes, which bounds the allocation for
+	// corrupt counts
+	if count > uint64(len(b)/2) {
+		return nil, fmt.Errorf("truncated vector series")
+	}
+	scale := math.Pow10(int(precision))
+
+	vs := make([]*Vector, 0, count)
+	var ts, delta, value int64
+	for i := uint64(0); i < count; i++ {
+		switch i {
+		case 0:
+			ts = readVarint()
+		case 1:
+			delta = readVarint()
+			ts += delta
+		default:
+			delta += readVarint()
+			ts += delta
+		}
+		value += readVarint()
+		if err != nil {
+			return nil, err
+		}
+
+		vs = append(vs, &Vector{
+			Timestamp: float64(ts) / 1000,
+			Value:     float64(value) / scale,
+		})
+	}
+
+	if len(b) > 0 {
+		return nil, fmt.Errorf("%d trailing bytes after vector series", len(b))
+	}
+
+	return vs, nil
+}
+
+// quantize rounds f * scale to the nearest integer
+func quantize(f float64, scale float64) (int64, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("unsupported value %f", f)
+	}
+	q := math.Round(f * scale)
+	if math.Abs(q) >= math.MaxInt64/2 {
+		return 0, fmt.Errorf("value %f too large to encode", f)
+	}
+	return int64(q), nil
+}
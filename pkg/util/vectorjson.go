@@ -0,0 +1,111 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// VectorPairs is a Vector slice which marshals to JSON in the compact pair
+// encoding, [[timestamp, value], ...], rather than as an array of
+// {"timestamp": ..., "value": ...} objects. It unmarshals from either form.
+type VectorPairs []*Vector
+
+// MarshalJSON encodes the vectors as [timestamp, value] pairs at full
+// precision. Use MarshalVectorPairs to round values.
+func (vp VectorPairs) MarshalJSON() ([]byte, error) {
+	return MarshalVectorPairs(vp, -1)
+}
+
+// UnmarshalJSON decodes either the pair encoding produced by MarshalJSON or
+// the default object encoding of a Vector slice.
+func (vp *VectorPairs) UnmarshalJSON(b []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	// null decodes to nil, so that nil vectors round trip
+	if raw == nil {
+		*vp = nil
+		return nil
+	}
+
+	vs := make([]*Vector, 0, len(raw))
+	for _, r := range raw {
+		r = bytes.TrimSpace(r)
+		if len(r) == 0 {
+			continue
+		}
+
+		if r[0] == '{' {
+			v := &Vector{}
+			if err := json.Unmarshal(r, v); err != nil {
+				return err
+			}
+			vs = append(vs, v)
+			continue
+		}
+
+		var pair []float64
+		if err := json.Unmarshal(r, &pair); err != nil {
+			return err
+		}
+		if len(pair) != 2 {
+			return fmt.Errorf("vector pair has %d elements, expected 2", len(pair))
+		}
+		vs = append(vs, &Vector{
+			Timestamp: pair[0],
+			Value:     pair[1],
+		})
+	}
+
+	*vp = vs
+	return nil
+}
+
+// MarshalVectorPairs encodes the vectors as [timestamp, value] pairs, with
+// values rounded to the given number of decimal places. A negative precision
+// leaves values unrounded. As with encoding/json, NaN and Inf values cannot be
+// represented and result in an error, as do nil vectors.
+func MarshalVectorPairs(vs []*Vector, precision int) ([]byte, error) {
+	if vs == nil {
+		return []byte("null"), nil
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, len(vs)*24))
+	buf.WriteByte('[')
+	for i, v := range vs {
+		if v == nil {
+			return nil, fmt.Errorf("nil vector at index %d", i)
+		}
+		if math.IsNaN(v.Value) || math.IsInf(v.Value, 0) {
+			return nil, fmt.Errorf("unsupported vector value at timestamp %f: %f", v.Timestamp, v.Value)
+		}
+
+		value := v.Value
+		if precision >= 0 {
+			value = roundFloat(value, precision)
+		}
+
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('[')
+		buf.WriteString(strconv.FormatFloat(v.Timestamp, 'f', -1, 64))
+		buf.WriteByte(',')
+		buf.WriteString(strconv.FormatFloat(value, 'f', -1, 64))
+		buf.WriteByte(']')
+	}
+	buf.WriteByte(']')
+
+	return buf.Bytes(), nil
+}
+
+// roundFloat rounds the value to the given number of decimal places.
+func roundFloat(value float64, precision int) float64 {
+	p := math.Pow(10, float64(precision))
+	return math.Round(value*p) / p
+}
@@ -1,6 +1,9 @@
 package test
 
 import (
+	"io/ioutil"
+	"os"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -256,3 +259,178 @@ func TestNodePriceFromCSVWithCase(t *testing.T) {
 	}
 
 }
+
+func TestCustomPricingValidate(t *testing.T) {
+	if err := cloud.DefaultPricing().Validate(); err != nil {
+		t.Errorf("Default pricing should be valid, got: %s", err)
+	}
+
+	cp := cloud.DefaultPricing()
+	cp.CPU = "0,12"
+	err := cp.Validate()
+	if err == nil || !strings.Contains(err.Error(), "CPU") {
+		t.Errorf("Expected error naming CPU for unparsable price, got: %v", err)
+	}
+
+	cp = cloud.DefaultPricing()
+	cp.Storage = "-0.01"
+	err = cp.Validate()
+	if err == nil || !strings.Contains(err.Error(), "Storage") {
+		t.Errorf("Expected error naming Storage for negative price, got: %v", err)
+	}
+
+	cp = cloud.DefaultPricing()
+	cp.CustomPricesEnabled = "true"
+	cp.CPU = "150"
+	err = cp.Validate()
+	if err == nil || !strings.Contains(err.Error(), "CPU") {
+		t.Errorf("Expected error for suspicious CPU price, got: %v", err)
+	}
+
+	cp = cloud.DefaultPricing()
+	cp.CustomPricesEnabled = "true"
+	cp.RAM = "0"
+	err = cp.Validate()
+	if err == nil || !strings.Contains(err.Error(), "RAM") {
+		t.Errorf("Expected error for zero RAM price, got: %v", err)
+	}
+
+	// a zero RAM price is only suspicious when custom prices are in use
+	cp.CustomPricesEnabled = "false"
+	if err := cp.Validate(); err != nil {
+		t.Errorf("Expected no error with custom prices disabled, got: %s", err)
+	}
+}
+
+func TestUpdateFromMapValidates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "providerconfig")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	oldConfigPath, hadConfigPath := os.LookupEnv("CONFIG_PATH")
+	os.Setenv("CONFIG_PATH", dir+"/")
+	defer func() {
+		if hadConfigPath {
+			os.Setenv("CONFIG_PATH", oldConfigPath)
+		} else {
+			os.Unsetenv("CONFIG_PATH")
+		}
+	}()
+
+	config := cloud.NewProviderConfig("pricing.json")
+
+	_, err = config.UpdateFromMap(map[string]string{"zoneNetworkEgress": "0,12"})
+	if err == nil || !strings.Contains(err.Error(), "ZoneNetworkEgress") {
+		t.Errorf("Expected error naming ZoneNetworkEgress, got: %v", err)
+	}
+
+	c, err := config.GetCustomPricingData()
+	if err != nil {
+		t.Fatalf("Error getting custom pricing: %s", err)
+	}
+	if c.ZoneNetworkEgress != cloud.DefaultPricing().ZoneNetworkEgress {
+		t.Errorf("Expected invalid price to be rejected, got '%s'", c.ZoneNetworkEgress)
+	}
+	if _, err := os.Stat(dir + "/pricing.json"); err == nil {
+		b, _ := ioutil.ReadFile(dir + "/pricing.json")
+		if strings.Contains(string(b), "0,12") {
+			t.Errorf("Expected invalid price not to be written to disk")
+		}
+	}
+
+	if _, err := config.UpdateFromMap(map[string]string{"zoneNetworkEgress": "0.12"}); err != nil {
+		t.Errorf("Expected valid update to succeed, got: %s", err)
+	}
+
+	// direct updates, as made by UpdateConfig, are validated too
+	_, err = config.Update(func(c *cloud.CustomPricing) error {
+		return cloud.SetCustomPricingField(c, "CPU", "0,12")
+	})
+	if err == nil || !strings.Contains(err.Error(), "CPU") {
+		t.Errorf("Expected error naming CPU, got: %v", err)
+	}
+	if c, _ := config.GetCustomPricingData(); c.CPU != cloud.DefaultPricing().CPU {
+		t.Errorf("Expected invalid CPU price to be rejected, got '%s'", c.CPU)
+	}
+
+	// a suspicious, but parsable, stored config must not block unrelated updates
+	_, err = config.Update(func(c *cloud.CustomPricing) error {
+		c.CustomPricesEnabled = "true"
+		c.RAM = "0"
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected suspicious prices to be accepted with a warning, got: %s", err)
+	}
+	c, err = config.UpdateFromMap(map[string]string{"clusterName": "cluster-one"})
+	if err != nil {
+		t.Errorf("Expected unrelated update to succeed, got: %s", err)
+	} else if c.ClusterName != "cluster-one" {
+		t.Errorf("Expected cluster name to be updated, got '%s'", c.ClusterName)
+	}
+}
+
+func TestCustomPricingHash(t *testing.T) {
+	a := cloud.DefaultPricing()
+	a.SharedCosts = map[string]string{"team": "platform", "env": "prod"}
+
+	b := cloud.DefaultPricing()
+	b.SharedCosts = make(map[string]string)
+	b.SharedCosts["env"] = "prod"
+	b.SharedCosts["team"] = "platform"
+
+	if a.Hash() != b.Hash() || !a.Equals(b) {
+		t.Errorf("Expected identical pricing to hash identically")
+	}
+
+	c := cloud.DefaultPricing()
+	d := cloud.DefaultPricing()
+	d.SharedCosts = map[string]string{}
+	if !c.Equals(d) {
+		t.Errorf("Expected nil and empty SharedCosts to be equal")
+	}
+
+	// changing any field other than the excluded ones must change the hash
+	excluded := map[string]bool{
+		"Provider":          true,
+		"Description":       true,
+		"ReadOnly":          true,
+		"ServiceKeyName":    true,
+		"ServiceKeySecret":  true,
+		"AzureClientID":     true,
+		"AzureClientSecret": true,
+		"AzureTenantID":     true,
+	}
+	base := cloud.DefaultPricing().Hash()
+	v := reflect.ValueOf(cloud.DefaultPricing()).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		p := cloud.DefaultPricing()
+		field := reflect.ValueOf(p).Elem().Field(i)
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(field.String() + "1")
+		case reflect.Map:
+			field.Set(reflect.ValueOf(map[string]string{"team": "platform"}))
+		default:
+			continue
+		}
+
+		name := v.Type().Field(i).Name
+		if excluded[name] && p.Hash() != base {
+			t.Errorf("Expected change to %s not to change the hash", name)
+		} else if !excluded[name] && p.Hash() == base {
+			t.Errorf("Expected change to %s to change the hash", name)
+		}
+	}
+
+	// values must not be able to shift between adjacent fields
+	e := cloud.DefaultPricing()
+	e.CPU, e.SpotCPU = "0.0316", "110.006655"
+	f := cloud.DefaultPricing()
+	f.CPU, f.SpotCPU = "0.03161", "10.006655"
+	if e.Equals(f) {
+		t.Errorf("Expected different field values to hash differently")
+	}
+}
@@ -0,0 +1,77 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/kubecost/cost-model/pkg/costmodel"
+)
+
+func TestInheritControllerLabels(t *testing.T) {
+	controllerLabels := costmodel.ControllerLabels{
+		"kubecost,deployment,cost-model,cluster-one": {
+			"team":                   "platform",
+			"owner":                  "controller",
+			"app_kubernetes_io_team": "controller",
+		},
+	}
+
+	podLabels := map[string]string{"app": "cost-model", "owner": "pod", "app.kubernetes.io/team": "pod"}
+
+	costData := map[string]*costmodel.CostData{
+		"merged": {
+			Namespace:   "kubecost",
+			ClusterID:   "cluster-one",
+			Deployments: []string{"cost-model"},
+			Labels:      podLabels,
+		},
+		"nil-labels": {
+			Namespace:   "kubecost",
+			ClusterID:   "cluster-one",
+			Deployments: []string{"cost-model"},
+		},
+		"no-controller": {
+			Namespace: "kubecost",
+			ClusterID: "cluster-one",
+			Labels:    map[string]string{"app": "standalone"},
+		},
+		"unknown-controller": {
+			Namespace:   "kubecost",
+			ClusterID:   "cluster-two",
+			Deployments: []string{"cost-model"},
+			Labels:      map[string]string{"app": "cost-model"},
+		},
+		"nil": nil,
+	}
+
+	costmodel.InheritControllerLabels(costData, controllerLabels)
+
+	merged := costData["merged"].Labels
+	if merged["team"] != "platform" {
+		t.Errorf("Expected controller label team=platform, got '%s'", merged["team"])
+	}
+	if merged["owner"] != "pod" {
+		t.Errorf("Expected pod label to win on conflict, got owner='%s'", merged["owner"])
+	}
+	if merged["app"] != "cost-model" {
+		t.Errorf("Expected pod label app to be kept, got '%s'", merged["app"])
+	}
+	if merged["app.kubernetes.io/team"] != "pod" {
+		t.Errorf("Expected raw pod label to be kept, got '%s'", merged["app.kubernetes.io/team"])
+	}
+	if v, ok := merged["app_kubernetes_io_team"]; ok {
+		t.Errorf("Expected controller label matching a raw pod label to be skipped, got '%s'", v)
+	}
+	if len(podLabels) != 3 {
+		t.Errorf("Expected the original pod labels to be unmodified, got %v", podLabels)
+	}
+
+	if ls := costData["nil-labels"].Labels; ls == nil || ls["team"] != "platform" || len(ls) != 3 {
+		t.Errorf("Expected controller labels on cost data with nil labels, got %v", ls)
+	}
+	if ls := costData["no-controller"].Labels; len(ls) != 1 {
+		t.Errorf("Expected labels to be untouched without a controller, got %v", ls)
+	}
+	if ls := costData["unknown-controller"].Labels; len(ls) != 1 {
+		t.Errorf("Expected labels to be untouched for an unknown controller, got %v", ls)
+	}
+}
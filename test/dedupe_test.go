@@ -0,0 +1,111 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/kubecost/cost-model/pkg/costmodel"
+	"github.com/kubecost/cost-model/pkg/util"
+)
+
+func hourlyVectors(startHour, endHour int, value float64) []*util.Vector {
+	vs := []*util.Vector{}
+	for h := startHour; h < endHour; h++ {
+		vs = append(vs, &util.Vector{Timestamp: float64(1588291200 + h*3600), Value: value})
+	}
+	return vs
+}
+
+func newContainerCostData(cpu []*util.Vector) *costmodel.CostData {
+	return &costmodel.CostData{
+		Name:          "container",
+		PodName:       "pod",
+		Namespace:     "namespace",
+		ClusterID:     "cluster-one",
+		CPUAllocation: cpu,
+	}
+}
+
+func TestDeduplicateCostData(t *testing.T) {
+	cases := []struct {
+		name        string
+		first       []*util.Vector
+		second      []*util.Vector
+		wantPoints  int
+		wantOverlap int
+	}{
+		{"identical", hourlyVectors(0, 4, 1.0), hourlyVectors(0, 4, 1.0), 4, 4},
+		{"partial overlap", hourlyVectors(0, 4, 1.0), hourlyVectors(2, 6, 2.0), 6, 2},
+		{"disjoint", hourlyVectors(0, 4, 1.0), hourlyVectors(4, 8, 1.0), 8, 0},
+	}
+
+	for _, c := range cases {
+		costData := map[string]*costmodel.CostData{
+			"a": newContainerCostData(c.first),
+			"b": newContainerCostData(c.second),
+		}
+
+		report := costmodel.DeduplicateCostData(costData)
+		if len(costData) != 1 {
+			t.Errorf("%s: expected 1 cost data entry, got %d", c.name, len(costData))
+			continue
+		}
+		if report.OverlappingPoints != c.wantOverlap {
+			t.Errorf("%s: expected %d overlapping points, got %d", c.name, c.wantOverlap, report.OverlappingPoints)
+		}
+		if merged := report.Merged["a"]; len(merged) != 1 || merged[0] != "b" {
+			t.Errorf("%s: expected 'b' to be merged into 'a', got %v", c.name, report.Merged)
+		}
+
+		cpu := costData["a"].CPUAllocation
+		if len(cpu) != c.wantPoints {
+			t.Errorf("%s: expected %d points, got %d", c.name, c.wantPoints, len(cpu))
+		}
+		// overlapping timestamps keep the first entry's value
+		if len(cpu) > 0 && cpu[0].Value != 1.0 {
+			t.Errorf("%s: expected first value 1.0, got %f", c.name, cpu[0].Value)
+		}
+	}
+
+	// different containers are left alone
+	other := newContainerCostData(hourlyVectors(0, 4, 1.0))
+	other.Name = "sidecar"
+	costData := map[string]*costmodel.CostData{
+		"a": newContainerCostData(hourlyVectors(0, 4, 1.0)),
+		"b": other,
+	}
+	report := costmodel.DeduplicateCostData(costData)
+	if len(costData) != 2 || len(report.Merged) != 0 {
+		t.Errorf("Expected distinct containers not to be merged")
+	}
+}
+
+func TestDeduplicateCostDataMergesVectors(t *testing.T) {
+	// the first entry saw the same sample twice at hour 0, e.g. from a
+	// Prometheus HA pair, and has a placeholder for missing data
+	first := append([]*util.Vector{{}}, hourlyVectors(0, 2, 1.0)...)
+	first = append(first, hourlyVectors(0, 1, 3.0)...)
+	costData := map[string]*costmodel.CostData{
+		"a": newContainerCostData(first),
+		"b": newContainerCostData(hourlyVectors(0, 3, 5.0)),
+	}
+
+	report := costmodel.DeduplicateCostData(costData)
+	if report.OverlappingPoints != 2 {
+		t.Errorf("Expected 2 overlapping points, got %d", report.OverlappingPoints)
+	}
+
+	cpu := costData["a"].CPUAllocation
+	if len(cpu) != 4 {
+		t.Fatalf("Expected 4 points including the placeholder, got %d", len(cpu))
+	}
+	if cpu[0].Timestamp != 0 {
+		t.Errorf("Expected the placeholder to be kept first, got timestamp %f", cpu[0].Timestamp)
+	}
+	// duplicates within the first entry are averaged, not summed
+	expected := []float64{2.0, 1.0, 5.0}
+	for i, v := range expected {
+		if cpu[i+1].Value != v {
+			t.Errorf("Expected value %f at point %d, got %f", v, i+1, cpu[i+1].Value)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kubecost/cost-model/pkg/costmodel/generator"
+	"github.com/kubecost/cost-model/pkg/util"
+)
+
+func TestGenerateCostData(t *testing.T) {
+	spec := generator.Spec{
+		Seed:                   7,
+		Namespaces:             3,
+		PodsPerNamespace:       4,
+		ContainersPerPod:       2,
+		Nodes:                  5,
+		Window:                 24 * time.Hour,
+		Resolution:             time.Hour,
+		Labels:                 map[string][]string{"team": {"a", "b"}, "env": {"prod", "dev"}},
+		SpotFraction:           0.5,
+		PVCFraction:            0.5,
+		MissingDataProbability: 0.1,
+	}
+
+	costData := generator.GenerateCostData(spec)
+	if len(costData) != 3*4*2 {
+		t.Fatalf("Expected %d cost data, got %d", 3*4*2, len(costData))
+	}
+
+	if !reflect.DeepEqual(costData, generator.GenerateCostData(spec)) {
+		t.Errorf("Expected equal specs to generate equal cost data")
+	}
+
+	for key, cd := range costData {
+		if len(cd.CPUAllocation) == 0 || len(cd.CPUAllocation) > 24 {
+			t.Errorf("%s: expected 1-24 CPU allocation points, got %d", key, len(cd.CPUAllocation))
+		}
+		if cd.NodeData == nil {
+			t.Errorf("%s: expected node data", key)
+		}
+		if _, ok := cd.Labels["team"]; !ok {
+			t.Errorf("%s: expected a team label", key)
+		}
+	}
+}
+
+func BenchmarkApplyVectorOp(b *testing.B) {
+	costData := generator.GenerateCostData(generator.Spec{
+		Seed:             1,
+		Namespaces:       10,
+		PodsPerNamespace: 10,
+		Window:           30 * 24 * time.Hour,
+		Resolution:       10 * time.Minute,
+	})
+
+	vectors := [][]*util.Vector{}
+	for _, cd := range costData {
+		vectors = append(vectors, cd.CPUAllocation)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var total []*util.Vector
+		for _, vs := range vectors {
+			total = util.ApplyVectorOp(total, vs, addVectorsOp)
+		}
+	}
+}
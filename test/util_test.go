@@ -3,6 +3,7 @@ package test
 import (
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/kubecost/cost-model/pkg/util"
 )
@@ -46,3 +47,80 @@ func TestNilHeader(t *testing.T) {
 
 	t.Logf("Result: %s\n", s)
 }
+
+func TestParseWindow(t *testing.T) {
+	// Wednesday
+	now := time.Date(2020, time.May, 13, 15, 30, 0, 0, time.UTC)
+	day := func(month time.Month, d int) time.Time {
+		return time.Date(2020, month, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	cases := []struct {
+		window string
+		start  time.Time
+		end    time.Time
+	}{
+		{"2d", now.Add(-48 * time.Hour), now},
+		{"90m", now.Add(-90 * time.Minute), now},
+		{"today", day(time.May, 13), now},
+		{"yesterday", day(time.May, 12), day(time.May, 13)},
+		{"week", day(time.May, 10), now},
+		{"lastweek", day(time.May, 3), day(time.May, 10)},
+		{"month", day(time.May, 1), now},
+		{"LastMonth", day(time.April, 1), day(time.May, 1)},
+		{"2020-05-01T00:00:00Z,2020-05-08T00:00:00Z", day(time.May, 1), day(time.May, 8)},
+	}
+
+	for _, c := range cases {
+		start, end, err := util.ParseWindow(c.window, now, time.UTC)
+		if err != nil {
+			t.Errorf("Window '%s': unexpected error: %s", c.window, err)
+			continue
+		}
+		if !start.Equal(c.start) || !end.Equal(c.end) {
+			t.Errorf("Window '%s': expected [%s, %s), got [%s, %s)", c.window, c.start, c.end, start, end)
+		}
+	}
+
+	for _, window := range []string{"", "fortnight", "-2h", "2020-05-08T00:00:00Z,2020-05-01T00:00:00Z"} {
+		if _, _, err := util.ParseWindow(window, now, time.UTC); err == nil {
+			t.Errorf("Window '%s': expected error", window)
+		}
+	}
+}
+
+func TestParseWindowDuration(t *testing.T) {
+	cases := map[string]time.Duration{
+		"2d":    48 * time.Hour,
+		"1h":    time.Hour,
+		"1h30m": 90 * time.Minute,
+	}
+	for window, expected := range cases {
+		dur, err := util.ParseWindowDuration(window)
+		if err != nil {
+			t.Errorf("Window '%s': unexpected error: %s", window, err)
+			continue
+		}
+		if dur != expected {
+			t.Errorf("Window '%s': expected %s, got %s", window, expected, dur)
+		}
+	}
+
+	for _, window := range []string{"", "d", "2w"} {
+		if _, err := util.ParseWindowDuration(window); err == nil {
+			t.Errorf("Window '%s': expected error", window)
+		}
+	}
+}
+
+func TestParseTimeRangeEmpty(t *testing.T) {
+	if _, err := util.ParseDuration(""); err == nil {
+		t.Errorf("Expected error parsing an empty duration")
+	}
+	if _, _, err := util.ParseTimeRange("", ""); err == nil {
+		t.Errorf("Expected error parsing an empty time range")
+	}
+	if _, _, err := util.ParseTimeRange("24h", "1d"); err != nil {
+		t.Errorf("Expected no error parsing a time range, got: %s", err)
+	}
+}
@@ -0,0 +1,188 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kubecost/cost-model/pkg/cloud"
+	"github.com/kubecost/cost-model/pkg/costmodel"
+	"github.com/kubecost/cost-model/pkg/util"
+)
+
+func hasIssue(issues []*costmodel.CostDataIssue, issueType costmodel.CostDataIssueType) bool {
+	for _, issue := range issues {
+		if issue.Type == issueType {
+			return true
+		}
+	}
+	return false
+}
+
+func TestValidateCostData(t *testing.T) {
+	end := time.Date(2020, time.May, 2, 0, 0, 0, 0, time.UTC)
+	start := end.Add(-24 * time.Hour)
+	step := time.Hour
+
+	inWindow := float64(start.Add(time.Hour).Unix())
+	beforeWindow := float64(start.Add(-3 * time.Hour).Unix())
+	yearsLater := float64(end.AddDate(3, 0, 0).Unix())
+
+	costData := map[string]*costmodel.CostData{
+		"healthy": {
+			ClusterID:     "cluster-one",
+			NodeName:      "node-1",
+			NodeData:      &cloud.Node{VCPUCost: "0.03", RAMCost: "0.004"},
+			CPUAllocation: []*util.Vector{{Timestamp: inWindow, Value: 1.0}},
+			// placeholder for missing request data
+			CPUReq: []*util.Vector{{}},
+		},
+		"bad-price": {
+			ClusterID:     "cluster-one",
+			NodeName:      "node-2",
+			NodeData:      &cloud.Node{VCPUCost: "0,03"},
+			CPUAllocation: []*util.Vector{{Timestamp: inWindow, Value: 1.0}},
+		},
+		"bad-vectors": {
+			ClusterID:     "cluster-one",
+			NodeName:      "node-1",
+			NodeData:      &cloud.Node{VCPUCost: "0.03"},
+			RAMAllocation: []*util.Vector{{Timestamp: beforeWindow, Value: 1024.0}, {Timestamp: inWindow, Value: -1.0}},
+			CPUAllocation: []*util.Vector{{Timestamp: yearsLater, Value: 1.0}, {Timestamp: 1e8, Value: 1.0}},
+		},
+		"no-node": {
+			ClusterID:     "cluster-two",
+			NodeName:      "node-3",
+			CPUAllocation: []*util.Vector{{Timestamp: inWindow, Value: 1.0}},
+		},
+		// ComputeCostDataRange inserts an empty node for nodes it could
+		// not find, which must count as missing node data
+		"empty-node": {
+			ClusterID:     "cluster-three",
+			NodeName:      "node-4",
+			NodeData:      &cloud.Node{},
+			CPUAllocation: []*util.Vector{{Timestamp: inWindow, Value: 1.0}},
+		},
+	}
+
+	report := costmodel.ValidateCostData(costData, start, end, step, 6*time.Hour)
+	if !report.HasIssues() {
+		t.Fatalf("Expected issues to be reported")
+	}
+
+	if _, ok := report.Data["healthy"]; ok {
+		t.Errorf("Expected no issues for healthy cost data, got %d", len(report.Data["healthy"]))
+	}
+	if !hasIssue(report.Data["bad-price"], costmodel.IssueUnparsableCost) {
+		t.Errorf("Expected unparsable cost issue")
+	}
+	if !hasIssue(report.Data["bad-vectors"], costmodel.IssueTimestampOutOfRange) {
+		t.Errorf("Expected out of range timestamp issue")
+	}
+	if !hasIssue(report.Data["bad-vectors"], costmodel.IssueFutureTimestamp) {
+		t.Errorf("Expected future timestamp issue")
+	}
+	if !hasIssue(report.Data["bad-vectors"], costmodel.IssueTimestampBeforeSane) {
+		t.Errorf("Expected timestamp before sane issue")
+	}
+	if !hasIssue(report.Data["bad-vectors"], costmodel.IssueNegativeValue) {
+		t.Errorf("Expected negative value issue")
+	}
+	if !hasIssue(report.Data["no-node"], costmodel.IssueMissingNodeData) {
+		t.Errorf("Expected missing node data issue")
+	}
+	if !hasIssue(report.Data["empty-node"], costmodel.IssueMissingNodeData) {
+		t.Errorf("Expected missing node data issue for a node without prices")
+	}
+	if !hasIssue(report.Clusters["cluster-three"], costmodel.IssueNoClusterNodeData) {
+		t.Errorf("Expected cluster-three to be reported as having no node data")
+	}
+	if !hasIssue(report.Clusters["cluster-two"], costmodel.IssueNoClusterNodeData) {
+		t.Errorf("Expected cluster-two to be reported as having no node data")
+	}
+	if _, ok := report.Clusters["cluster-one"]; ok {
+		t.Errorf("Expected no cluster issues for cluster-one")
+	}
+}
+
+func TestValidateCostDataIssueOrder(t *testing.T) {
+	end := time.Date(2020, time.May, 2, 0, 0, 0, 0, time.UTC)
+	start := end.Add(-24 * time.Hour)
+	negative := []*util.Vector{{Timestamp: float64(start.Add(time.Hour).Unix()), Value: -1.0}}
+
+	costData := map[string]*costmodel.CostData{
+		"negative": {
+			ClusterID:     "cluster-one",
+			NodeData:      &cloud.Node{VCPUCost: "0.03"},
+			RAMReq:        negative,
+			CPUReq:        negative,
+			CPUAllocation: negative,
+			NetworkData:   negative,
+			PVCData: []*costmodel.PersistentVolumeClaimData{
+				{Claim: "claim-b", Values: negative},
+				{Claim: "claim-a", Values: negative},
+			},
+		},
+	}
+
+	expected := []string{
+		"RAMReq has 1 negative values",
+		"CPUReq has 1 negative values",
+		"CPUAllocation has 1 negative values",
+		"NetworkData has 1 negative values",
+		"PVCData[claim-b] has 1 negative values",
+		"PVCData[claim-a] has 1 negative values",
+	}
+
+	// repeat, as the order of a map range would differ between runs
+	for i := 0; i < 10; i++ {
+		issues := costmodel.ValidateCostData(costData, start, end, time.Hour, time.Hour).Data["negative"]
+		if len(issues) != len(expected) {
+			t.Fatalf("Expected %d issues, got %d", len(expected), len(issues))
+		}
+		for j, issue := range issues {
+			if issue.Message != expected[j] {
+				t.Fatalf("Expected issue %d to be '%s', got '%s'", j, expected[j], issue.Message)
+			}
+		}
+	}
+}
+
+func TestDropSkewedCostData(t *testing.T) {
+	end := time.Date(2020, time.May, 2, 0, 0, 0, 0, time.UTC)
+	tolerance := 10 * time.Minute
+
+	inWindow := float64(end.Add(-time.Hour).Unix())
+	withinTolerance := float64(end.Add(5 * time.Minute).Unix())
+	skewed := float64(end.Add(2 * time.Hour).Unix())
+
+	costData := map[string]*costmodel.CostData{
+		"skewed": {
+			CPUAllocation: []*util.Vector{
+				{Timestamp: inWindow, Value: 1.0},
+				{Timestamp: withinTolerance, Value: 1.0},
+				{Timestamp: skewed, Value: 1.0},
+			},
+			PVCData: []*costmodel.PersistentVolumeClaimData{
+				{Claim: "claim", Values: []*util.Vector{{Timestamp: 1e8, Value: 1024.0}, {Timestamp: inWindow, Value: 1024.0}}},
+			},
+			// placeholder for missing request data
+			CPUReq: []*util.Vector{{}},
+		},
+	}
+
+	dropped := costmodel.DropSkewedCostData(costData, end, tolerance)
+	if dropped != 2 {
+		t.Errorf("Expected 2 points dropped, got %d", dropped)
+	}
+
+	cd := costData["skewed"]
+	if len(cd.CPUAllocation) != 2 || cd.CPUAllocation[1].Timestamp != withinTolerance {
+		t.Errorf("Expected CPU points up to the tolerance to be kept, got %d", len(cd.CPUAllocation))
+	}
+	if len(cd.PVCData[0].Values) != 1 || cd.PVCData[0].Values[0].Timestamp != inWindow {
+		t.Errorf("Expected the pre-2000 PVC point to be dropped")
+	}
+	if len(cd.CPUReq) != 1 || cd.CPUReq[0].Timestamp != 0 {
+		t.Errorf("Expected the missing data placeholder to be kept, got %d points", len(cd.CPUReq))
+	}
+}
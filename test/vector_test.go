@@ -0,0 +1,453 @@
+package test
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/kubecost/cost-model/pkg/util"
+)
+
+func TestVectorPairsRoundTrip(t *testing.T) {
+	vs := []*util.Vector{
+		{Timestamp: 1588291200, Value: 0.1 + 0.2},
+		{Timestamp: 1588294800, Value: 12.3456789},
+	}
+
+	b, err := json.Marshal(util.VectorPairs(vs))
+	if err != nil {
+		t.Fatalf("Error marshaling vector pairs: %s", err)
+	}
+
+	var pairs util.VectorPairs
+	if err := json.Unmarshal(b, &pairs); err != nil {
+		t.Fatalf("Error unmarshaling vector pairs: %s", err)
+	}
+	if len(pairs) != len(vs) {
+		t.Fatalf("Expected %d vectors, got %d", len(vs), len(pairs))
+	}
+	for i := range vs {
+		if *pairs[i] != *vs[i] {
+			t.Errorf("Vector %d: expected %+v, got %+v", i, *vs[i], *pairs[i])
+		}
+	}
+
+	// the default object encoding must also be accepted
+	b, err = json.Marshal(vs)
+	if err != nil {
+		t.Fatalf("Error marshaling vectors: %s", err)
+	}
+	if err := json.Unmarshal(b, &pairs); err != nil {
+		t.Fatalf("Error unmarshaling vector objects: %s", err)
+	}
+	if len(pairs) != len(vs) || *pairs[1] != *vs[1] {
+		t.Errorf("Expected %+v, got %+v", *vs[1], *pairs[1])
+	}
+}
+
+func TestMarshalVectorPairsPrecision(t *testing.T) {
+	vs := []*util.Vector{
+		{Timestamp: 1588291200, Value: 0.1 + 0.2},
+		{Timestamp: 1588294800, Value: 12.3456789},
+	}
+
+	b, err := util.MarshalVectorPairs(vs, 2)
+	if err != nil {
+		t.Fatalf("Error marshaling vector pairs: %s", err)
+	}
+
+	want := `[[1588291200,0.3],[1588294800,12.35]]`
+	if string(b) != want {
+		t.Errorf("Expected %s, got %s", want, string(b))
+	}
+}
+
+func TestVectorPairsNil(t *testing.T) {
+	var vs util.VectorPairs
+
+	b, err := json.Marshal(vs)
+	if err != nil {
+		t.Fatalf("Error marshaling nil vector pairs: %s", err)
+	}
+	if string(b) != "null" {
+		t.Errorf("Expected null, got %s", string(b))
+	}
+
+	pairs := util.VectorPairs{}
+	if err := json.Unmarshal(b, &pairs); err != nil {
+		t.Fatalf("Error unmarshaling null vector pairs: %s", err)
+	}
+	if pairs != nil {
+		t.Errorf("Expected null to unmarshal to nil, got %v", pairs)
+	}
+
+	if _, err := util.MarshalVectorPairs([]*util.Vector{{Timestamp: 1588291200, Value: 1.0}, nil}, -1); err == nil {
+		t.Errorf("Expected an error marshaling a nil vector")
+	}
+}
+
+func addVectorsOp(result *util.Vector, x *float64, y *float64) bool {
+	if x != nil && y != nil {
+		result.Value = *x + *y
+	} else if x != nil {
+		result.Value = *x
+	} else if y != nil {
+		result.Value = *y
+	}
+
+	return true
+}
+
+func totalVectors(vs []*util.Vector) float64 {
+	total := 0.0
+	for _, v := range vs {
+		total += v.Value
+	}
+	return total
+}
+
+func TestApplyVectorOpDuplicateTimestamps(t *testing.T) {
+	// x holds two samples which round to the same timestamp, e.g. from an
+	// HA pair of Prometheus servers
+	newX := func(reverse bool) []*util.Vector {
+		xvs := []*util.Vector{
+			{Timestamp: 1588291200, Value: 1.0},
+			{Timestamp: 1588291202, Value: 2.0},
+			{Timestamp: 1588294800, Value: 4.0},
+		}
+		if reverse {
+			xvs[0], xvs[1] = xvs[1], xvs[0]
+		}
+		return xvs
+	}
+	newY := func() []*util.Vector {
+		return []*util.Vector{
+			{Timestamp: 1588291200, Value: 8.0},
+			{Timestamp: 1588298400, Value: 16.0},
+			{Timestamp: 1588298401, Value: 32.0},
+		}
+	}
+
+	for _, reverse := range []bool{false, true} {
+		result := util.ApplyVectorOp(newX(reverse), newY(), addVectorsOp)
+		if len(result) != 3 {
+			t.Fatalf("Expected 3 vectors, got %d", len(result))
+		}
+		if total := totalVectors(result); total != 63.0 {
+			t.Errorf("Expected total 63.0, got %f", total)
+		}
+		if result[0].Value != 11.0 {
+			t.Errorf("Expected 11.0 at first timestamp, got %f", result[0].Value)
+		}
+
+		averaged := util.ApplyVectorOpWithPolicy(newX(reverse), newY(), addVectorsOp, util.DuplicateAverage)
+		if len(averaged) != 3 || averaged[0].Value != 9.5 || averaged[2].Value != 24.0 {
+			t.Errorf("Unexpected averaged vectors: %+v, %+v", *averaged[0], *averaged[2])
+		}
+	}
+
+	// when the other input is empty, the input is returned as is, duplicates
+	// and placeholders included
+	x := append(newX(false), &util.Vector{})
+	if result := util.ApplyVectorOp(x, nil, addVectorsOp); len(result) != len(x) || result[0] != x[0] {
+		t.Errorf("Expected an input joined with an empty input to be returned as is")
+	}
+	y := newY()
+	if result := util.ApplyVectorOpWithPolicy(nil, y, addVectorsOp, util.DuplicateAverage); len(result) != len(y) || result[2] != y[2] {
+		t.Errorf("Expected an input joined with an empty input to be returned as is")
+	}
+}
+
+func TestNormalizeVectorByVectorDuplicates(t *testing.T) {
+	xvs := []*util.Vector{{Timestamp: 1588291200, Value: 4.0}}
+	// the unit gauge is reported twice, e.g. by an HA pair
+	yvs := []*util.Vector{
+		{Timestamp: 1588291200, Value: 2.0},
+		{Timestamp: 1588291201, Value: 2.0},
+	}
+
+	normalized := util.NormalizeVectorByVector(xvs, yvs)
+	if len(normalized) != 1 || normalized[0].Value != 2.0 {
+		t.Errorf("Expected 4.0 normalized by 2.0 to be 2.0, got %+v", normalized)
+	}
+}
+
+func TestDeduplicateVectors(t *testing.T) {
+	vs := []*util.Vector{
+		{Timestamp: 1588294800, Value: 4.0},
+		{Timestamp: 1588291200, Value: 1.0},
+		{Timestamp: 1588291202, Value: 2.0},
+	}
+
+	summed := util.DeduplicateVectors(vs, util.DuplicateSum)
+	if len(summed) != 2 || summed[0].Value != 3.0 || summed[1].Value != 4.0 {
+		t.Errorf("Unexpected summed vectors: %+v, %+v", *summed[0], *summed[1])
+	}
+
+	averaged := util.DeduplicateVectors(vs, util.DuplicateAverage)
+	if len(averaged) != 2 || math.Abs(averaged[0].Value-1.5) > 1e-9 || averaged[1].Value != 4.0 {
+		t.Errorf("Unexpected averaged vectors: %+v, %+v", *averaged[0], *averaged[1])
+	}
+
+	// input must be left untouched
+	if vs[2].Timestamp != 1588291202 || vs[2].Value != 2.0 {
+		t.Errorf("Input vector was modified: %+v", *vs[2])
+	}
+
+	// zero timestamps mark missing data and are kept as they are
+	placeholders := util.DeduplicateVectors([]*util.Vector{{}, {}, {Timestamp: 1588291200, Value: 1.0}}, util.DuplicateAverage)
+	if len(placeholders) != 3 || placeholders[0].Timestamp != 0 || placeholders[1].Timestamp != 0 {
+		t.Errorf("Expected zero timestamps to be kept, got %d vectors", len(placeholders))
+	}
+}
+
+func TestTrimVectors(t *testing.T) {
+	start, end, step := 3600.0, 3.0*3600.0, 3600.0
+
+	// Prometheus commonly returns one sample either side of the window
+	vs := []*util.Vector{
+		{Timestamp: 1800, Value: 1.0},
+		{Timestamp: 3600, Value: 1.0},
+		{Timestamp: 7200, Value: 1.0},
+		{Timestamp: 9000, Value: 1.0},
+		{Timestamp: 10800, Value: 1.0},
+	}
+
+	trimmed := util.TrimVectors(vs, start, end, step, false)
+	if len(trimmed) != 3 || trimmed[0].Timestamp != 3600 || trimmed[2].Timestamp != 9000 {
+		t.Errorf("Expected vectors at 3600, 7200, 9000; got %d vectors", len(trimmed))
+	}
+
+	prorated := util.TrimVectors(vs, start, end, step, true)
+	if len(prorated) != 4 {
+		t.Fatalf("Expected 4 prorated vectors, got %d", len(prorated))
+	}
+	if prorated[0].Timestamp != start || prorated[0].Value != 0.5 {
+		t.Errorf("Expected leading vector {%f 0.5}, got %+v", start, *prorated[0])
+	}
+	if prorated[3].Value != 0.5 {
+		t.Errorf("Expected trailing vector value 0.5, got %f", prorated[3].Value)
+	}
+	if total := totalVectors(prorated); total != 3.0 {
+		t.Errorf("Expected prorated total of 3.0, got %f", total)
+	}
+
+	if vs[0].Value != 1.0 || vs[0].Timestamp != 1800 {
+		t.Errorf("Input vector was modified: %+v", *vs[0])
+	}
+
+	// missing data placeholders are passed through in both modes
+	for _, prorate := range []bool{false, true} {
+		placeholder := util.TrimVectors([]*util.Vector{{}}, start, end, step, prorate)
+		if len(placeholder) != 1 || placeholder[0].Timestamp != 0 {
+			t.Errorf("Expected placeholder to be kept with prorate %t, got %d vectors", prorate, len(placeholder))
+		}
+	}
+}
+
+func TestResampleVectors(t *testing.T) {
+	// 1-minute samples summed into 5-minute steps
+	fine := []*util.Vector{}
+	for i := 0; i < 10; i++ {
+		fine = append(fine, &util.Vector{Timestamp: float64(1588291200 + i*60), Value: 1.0})
+	}
+
+	resampled := util.ResampleVectors(fine, 60, 300)
+	if len(resampled) != 2 {
+		t.Fatalf("Expected 2 vectors, got %d", len(resampled))
+	}
+	if resampled[0].Timestamp != 1588291200 || resampled[0].Value != 5.0 || resampled[1].Timestamp != 1588291500 || resampled[1].Value != 5.0 {
+		t.Errorf("Unexpected resampled vectors: %+v, %+v", *resampled[0], *resampled[1])
+	}
+
+	// 5-minute samples spread across 1-minute steps
+	coarse := []*util.Vector{
+		{Timestamp: 1588291200, Value: 5.0},
+		{Timestamp: 1588291500, Value: 10.0},
+	}
+
+	resampled = util.ResampleVectors(coarse, 300, 60)
+	if len(resampled) != 10 {
+		t.Fatalf("Expected 10 vectors, got %d", len(resampled))
+	}
+	if resampled[0].Value != 1.0 || resampled[9].Value != 2.0 {
+		t.Errorf("Unexpected resampled values: %f, %f", resampled[0].Value, resampled[9].Value)
+	}
+	if total := totalVectors(resampled); math.Abs(total-15.0) > 1e-9 {
+		t.Errorf("Expected total of 15.0, got %f", total)
+	}
+
+	// unaligned samples are split across the steps they straddle
+	resampled = util.ResampleVectors([]*util.Vector{{Timestamp: 150, Value: 4.0}}, 300, 300)
+	if len(resampled) != 2 || resampled[0].Value != 2.0 || resampled[1].Value != 2.0 {
+		t.Errorf("Expected value split evenly across two steps, got %d vectors", len(resampled))
+	}
+
+	// invalid, unrepresentable or excessively fine steps return a copy of the
+	// input
+	vs := []*util.Vector{{Timestamp: 1.6e9, Value: 1.0}}
+	for _, c := range []struct{ from, to float64 }{{300, 0}, {300, 1e-7}, {1e8, 1}} {
+		resampled = util.ResampleVectors(vs, c.from, c.to)
+		if len(resampled) != 1 || resampled[0] == vs[0] || *resampled[0] != *vs[0] {
+			t.Errorf("Expected a copy of the input resampling from %g to %g", c.from, c.to)
+		}
+	}
+
+	// missing data placeholders are passed through
+	resampled = util.ResampleVectors([]*util.Vector{{}, {Timestamp: 600, Value: 2.0}}, 600, 300)
+	if len(resampled) != 3 || resampled[0].Timestamp != 0 || resampled[1].Value != 1.0 {
+		t.Errorf("Expected placeholder to be kept, got %d vectors", len(resampled))
+	}
+}
+
+// usageVectors returns a month of hourly usage-like vectors
+func usageVectors() []*util.Vector {
+	rng := rand.New(rand.NewSource(1))
+	vs := make([]*util.Vector, 0, 720)
+	for h := 0; h < 720; h++ {
+		vs = append(vs, &util.Vector{
+			Timestamp: float64(1588291200 + h*3600),
+			Value:     0.5 + rng.Float64()*0.5,
+		})
+	}
+	return vs
+}
+
+func TestVectorSeriesCodec(t *testing.T) {
+	vs := usageVectors()
+	// an irregular step and negative values must survive too
+	vs = append(vs, &util.Vector{Timestamp: 1590883200.5, Value: -2.25})
+
+	codec := util.VectorSeriesCodec{Precision: 4}
+	b, err := codec.Encode(vs)
+	if err != nil {
+		t.Fatalf("Error encoding vectors: %s", err)
+	}
+
+	decoded, err := codec.Decode(b)
+	if err != nil {
+		t.Fatalf("Error decoding vectors: %s", err)
+	}
+	if len(decoded) != len(vs) {
+		t.Fatalf("Expected %d vectors, got %d", len(vs), len(decoded))
+	}
+	for i := range vs {
+		if decoded[i].Timestamp != vs[i].Timestamp {
+			t.Errorf("Vector %d: expected timestamp %f, got %f", i, vs[i].Timestamp, decoded[i].Timestamp)
+		}
+		if math.Abs(decoded[i].Value-vs[i].Value) > 0.00005 {
+			t.Errorf("Vector %d: expected value %f, got %f", i, vs[i].Value, decoded[i].Value)
+		}
+	}
+
+	// rounding errors are bounded by half a unit of precision per point
+	maxError := float64(len(vs)) * 0.00005
+	if diff := math.Abs(totalVectors(decoded) - totalVectors(vs)); diff > maxError {
+		t.Errorf("Expected totals within %f, got difference %f", maxError, diff)
+	}
+
+	js, err := json.Marshal(vs)
+	if err != nil {
+		t.Fatalf("Error marshaling vectors: %s", err)
+	}
+	if ratio := float64(len(js)) / float64(len(b)); ratio < 10 {
+		t.Errorf("Expected at least 10x compression over JSON, got %.1fx", ratio)
+	}
+
+	empty, err := codec.Encode(nil)
+	if err != nil {
+		t.Fatalf("Error encoding empty vectors: %s", err)
+	}
+	if decoded, err := codec.Decode(empty); err != nil || len(decoded) != 0 {
+		t.Errorf("Expected empty vectors, got %v, %v", decoded, err)
+	}
+
+	if _, err := codec.Encode([]*util.Vector{{Timestamp: 1588291200, Value: math.NaN()}}); err == nil {
+		t.Errorf("Expected an error encoding NaN")
+	}
+	if _, err := codec.Decode(b[:len(b)-1]); err == nil {
+		t.Errorf("Expected an error decoding truncated data")
+	}
+	if _, err := codec.Decode(append([]byte{0}, b[1:]...)); err == nil {
+		t.Errorf("Expected an error decoding an unknown version")
+	}
+}
+
+func BenchmarkVectorSeriesCodec(b *testing.B) {
+	vs := usageVectors()
+	codec := util.VectorSeriesCodec{Precision: 4}
+
+	js, _ := json.Marshal(vs)
+	encoded, err := codec.Encode(vs)
+	if err != nil {
+		b.Fatalf("Error encoding vectors: %s", err)
+	}
+	b.ReportMetric(float64(len(encoded)), "bytes")
+	b.ReportMetric(float64(len(js))/float64(len(encoded)), "ratio")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		encoded, _ = codec.Encode(vs)
+		codec.Decode(encoded)
+	}
+}
+
+func TestMaxMinVectors(t *testing.T) {
+	requests := []*util.Vector{
+		{Timestamp: 1588291200, Value: 2.0},
+		{Timestamp: 1588294800, Value: 2.0},
+		{Timestamp: 1588298400, Value: 2.0},
+	}
+	// usage is missing the last timestamp and has one the requests lack,
+	// slightly off so that it only matches after rounding
+	usage := []*util.Vector{
+		{Timestamp: 1588291203, Value: 1.0},
+		{Timestamp: 1588294800, Value: 3.0},
+		{Timestamp: 1588302000, Value: 0.5},
+	}
+
+	max := util.MaxVectors(requests, usage)
+	wantMax := []float64{2.0, 3.0, 2.0, 0.5}
+	if len(max) != len(wantMax) {
+		t.Fatalf("Expected %d max vectors, got %d", len(wantMax), len(max))
+	}
+	for i, want := range wantMax {
+		if max[i].Value != want {
+			t.Errorf("Max vector %d: expected %f, got %f", i, want, max[i].Value)
+		}
+	}
+
+	min := util.MinVectors(requests, usage)
+	wantMin := []float64{1.0, 2.0}
+	if len(min) != len(wantMin) {
+		t.Fatalf("Expected %d min vectors, got %d", len(wantMin), len(min))
+	}
+	for i, want := range wantMin {
+		if min[i].Value != want {
+			t.Errorf("Min vector %d: expected %f, got %f", i, want, min[i].Value)
+		}
+	}
+
+	if min := util.MinVectors(requests, nil); len(min) != 0 {
+		t.Errorf("Expected no min vectors against an empty input, got %d", len(min))
+	}
+	if max := util.MaxVectors(nil, usage); len(max) != len(usage) {
+		t.Errorf("Expected max against an empty input to pass through, got %d", len(max))
+	}
+
+	// duplicate samples of a gauge, e.g. from an HA pair, are averaged
+	duplicated := []*util.Vector{
+		{Timestamp: 1588291200, Value: 1.0},
+		{Timestamp: 1588291200, Value: 1.0},
+	}
+	if max := util.MaxVectors(duplicated, []*util.Vector{{Timestamp: 1588291200, Value: 1.5}}); len(max) != 1 || max[0].Value != 1.5 {
+		t.Errorf("Expected duplicates to be averaged before taking the max")
+	}
+
+	// NaN propagates
+	nan := []*util.Vector{{Timestamp: 1588291200, Value: math.NaN()}}
+	if max := util.MaxVectors(nan, []*util.Vector{{Timestamp: 1588291200, Value: 1.0}}); len(max) != 1 || !math.IsNaN(max[0].Value) {
+		t.Errorf("Expected NaN to propagate through max")
+	}
+}